@@ -1,7 +1,56 @@
 package main
 
-import "whenchangesth/internal/handler"
+import (
+	"flag"
+	"fmt"
+	"log"
+	"os"
+	"whenchangesth/internal/conf"
+	"whenchangesth/internal/handler"
+)
+
+const defaultConfigPath = "/app-acc/configs/config.yaml"
 
 func main() {
-	handler.SetupHTTP()
+	if len(os.Args) > 1 {
+		switch os.Args[1] {
+		case "rules":
+			if err := runRules(os.Args[2:]); err != nil {
+				log.Fatal(err)
+			}
+			return
+		case "migrate":
+			if err := runMigrate(os.Args[2:]); err != nil {
+				log.Fatal(err)
+			}
+			return
+		case "replay":
+			if err := runReplay(os.Args[2:]); err != nil {
+				log.Fatal(err)
+			}
+			return
+		}
+	}
+
+	configPath := flag.String("config", defaultConfigPath, "配置文件路径，配置项可被 JIRAHOOK_* 环境变量覆盖")
+	flag.Parse()
+
+	cfg := loadConfig(*configPath)
+	if err := handler.SetupHTTP(cfg); err != nil {
+		log.Fatal(err)
+	}
+}
+
+// loadConfig 加载并校验配置，有问题时一次性输出全部问题后退出
+func loadConfig(filePath string) *conf.Config {
+	cfg, err := conf.Load(filePath)
+	if err == nil {
+		err = cfg.Validate()
+	}
+	if err != nil {
+		fmt.Fprintln(os.Stderr, "配置无效:")
+		fmt.Fprintln(os.Stderr, err)
+		os.Exit(2)
+	}
+	return cfg
 }
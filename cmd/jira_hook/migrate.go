@@ -0,0 +1,55 @@
+package main
+
+import (
+	"context"
+	"flag"
+	"fmt"
+	"whenchangesth/internal/store"
+)
+
+// runMigrate 实现 migrate 子命令：执行尚未执行的数据库迁移，-status 时只列出执行情况
+func runMigrate(args []string) error {
+	fs := flag.NewFlagSet("migrate", flag.ExitOnError)
+	configPath := fs.String("config", defaultConfigPath, "配置文件路径")
+	status := fs.Bool("status", false, "只列出各迁移的执行情况")
+	fs.Usage = func() {
+		fmt.Fprintln(fs.Output(), "用法: jira_hook migrate [-config 配置文件] [-status]")
+		fs.PrintDefaults()
+	}
+	_ = fs.Parse(args)
+
+	cfg := loadConfig(*configPath)
+	ctx := context.Background()
+	db, err := store.Open(ctx, cfg.MySQL)
+	if err != nil {
+		return err
+	}
+	defer db.Close()
+
+	if *status {
+		list, err := store.Status(ctx, db)
+		if err != nil {
+			return err
+		}
+		for _, s := range list {
+			applied := "未执行"
+			if s.AppliedAt != nil {
+				applied = s.AppliedAt.Format("2006-01-02 15:04:05")
+			}
+			fmt.Printf("%04d_%s\t%s\n", s.Version, s.Name, applied)
+		}
+		return nil
+	}
+
+	applied, err := store.Migrate(ctx, db)
+	for _, m := range applied {
+		fmt.Printf("已执行 %04d_%s\n", m.Version, m.Name)
+	}
+	if err != nil {
+		return err
+	}
+	if len(applied) == 0 {
+		fmt.Println("没有需要执行的迁移")
+	}
+	return nil
+}
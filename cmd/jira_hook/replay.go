@@ -0,0 +1,57 @@
+package main
+
+import (
+	"flag"
+	"fmt"
+	"os"
+	"time"
+	"whenchangesth/internal/handler"
+)
+
+// runReplay 实现 replay 子命令：将存档的 webhook 按收到的顺序重新交给事件处理器
+func runReplay(args []string) error {
+	fs := flag.NewFlagSet("replay", flag.ExitOnError)
+	configPath := fs.String("config", defaultConfigPath, "配置文件路径")
+	since := fs.String("since", "", "只重放此时间之后收到的 webhook，RFC3339 或 2006-01-02")
+	until := fs.String("until", "", "只重放此时间之前收到的 webhook，RFC3339 或 2006-01-02")
+	issue := fs.String("issue", "", "只重放该问题的 webhook，例如 ABC-123")
+	event := fs.String("event", "", "只重放该类型的 webhook，例如 jira:issue_updated")
+	dryRun := fs.Bool("dry-run", false, "只输出渲染后的消息，不写入 Redis 和 MySQL，也不发送")
+	fs.Usage = func() {
+		fmt.Fprintln(fs.Output(), "用法: jira_hook replay [-config 配置文件] [-since 时间] [-until 时间] [-issue 问题] [-event 事件] [-dry-run]")
+		fs.PrintDefaults()
+	}
+	_ = fs.Parse(args)
+
+	var filter handler.ArchiveFilter
+	var err error
+	if filter.Since, err = parseFlagTime(*since); err != nil {
+		return fmt.Errorf("-since 无效: %v", err)
+	}
+	if filter.Until, err = parseFlagTime(*until); err != nil {
+		return fmt.Errorf("-until 无效: %v", err)
+	}
+	filter.IssueKey, filter.Event = *issue, *event
+
+	// 避免误将全部存档重新发送一遍
+	if !*dryRun && filter == (handler.ArchiveFilter{}) {
+		fmt.Fprintln(os.Stderr, "重放全部存档时需要加上 -dry-run，或用 -since、-until、-issue、-event 缩小范围")
+		os.Exit(2)
+	}
+
+	cfg := loadConfig(*configPath)
+	replayed, skipped, err := handler.Replay(cfg, handler.ReplayOptions{Filter: filter, DryRun: *dryRun, Out: os.Stdout})
+	fmt.Printf("共重放 %d 条，跳过 %d 条\n", replayed, skipped)
+	return err
+}
+
+// parseFlagTime 解析 RFC3339 时间或本地日期，空字符串返回零值
+func parseFlagTime(s string) (time.Time, error) {
+	if s == "" {
+		return time.Time{}, nil
+	}
+	if t, err := time.Parse(time.RFC3339, s); err == nil {
+		return t, nil
+	}
+	return time.ParseInLocation(time.DateOnly, s, time.Local)
+}
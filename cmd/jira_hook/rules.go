@@ -0,0 +1,80 @@
+package main
+
+import (
+	"flag"
+	"fmt"
+	"os"
+	"strings"
+	"whenchangesth/internal/handler"
+	"whenchangesth/internal/rules"
+)
+
+// runRules 实现 rules 子命令：用配置中的规则评估保存下来的 payload，不连接 Redis 也不发送通知
+func runRules(args []string) error {
+	fs := flag.NewFlagSet("rules", flag.ExitOnError)
+	configPath := fs.String("config", defaultConfigPath, "配置文件路径")
+	fs.Usage = func() {
+		fmt.Fprintln(fs.Output(), "用法: jira_hook rules [-config 配置文件] payload.json...")
+		fs.PrintDefaults()
+	}
+	_ = fs.Parse(args)
+	if fs.NArg() == 0 {
+		fs.Usage()
+		os.Exit(2)
+	}
+
+	cfg := loadConfig(*configPath)
+	for _, filePath := range fs.Args() {
+		body, err := os.ReadFile(filePath)
+		if err != nil {
+			return err
+		}
+		payload, err := handler.ParseWebhook(body)
+		if err != nil {
+			return fmt.Errorf("%s: 解析 payload 失败: %v", filePath, err)
+		}
+		traces, err := handler.TraceRules(cfg, payload)
+		if err != nil {
+			return fmt.Errorf("%s: %v", filePath, err)
+		}
+
+		fmt.Printf("%s:\n", filePath)
+		if len(traces) == 0 {
+			fmt.Println("  没有可评估的变更记录")
+		}
+		for _, trace := range traces {
+			printTrace(trace)
+		}
+	}
+	return nil
+}
+
+func printTrace(trace handler.RuleTrace) {
+	fmt.Printf("  [%s]\n", trace.EventType)
+	for _, f := range trace.Facts {
+		fmt.Printf("    %s\n", describeFacts(f))
+	}
+
+	d := trace.Decision
+	if len(d.Matched) == 0 {
+		fmt.Println("    命中规则: 无")
+	} else {
+		fmt.Printf("    命中规则: %s\n", strings.Join(d.Matched, ", "))
+	}
+	switch {
+	case d.Skipped:
+		fmt.Println("    结果: 跳过")
+	case !d.Notify:
+		fmt.Println("    结果: 不通知")
+	default:
+		fmt.Printf("    结果: 通知 robots=%v mention=%v immediate=%t\n", trace.Robots, d.Mention, d.Immediate)
+	}
+}
+
+func describeFacts(f rules.Facts) string {
+	desc := f.Event
+	if f.Field != "" {
+		desc += fmt.Sprintf(" %s: %q → %q", f.Field, f.From, f.To)
+	}
+	return desc + fmt.Sprintf(" (project=%s priority=%s issue_type=%s labels=%v)", f.Project, f.Priority, f.IssueType, f.Labels)
+}
@@ -0,0 +1,10 @@
+package conf
+
+import "time"
+
+// ArchiveConfig 定义 webhook 原始请求存档配置部分，存档可用 replay 子命令重放
+type ArchiveConfig struct {
+	Enabled bool `yaml:"enabled"`
+	// Retention 是存档保留时间，0 表示不清理
+	Retention time.Duration `yaml:"retention"`
+}
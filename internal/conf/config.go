@@ -0,0 +1,194 @@
+package conf
+
+import (
+	"fmt"
+	"os"
+	"reflect"
+	"strconv"
+	"strings"
+	"time"
+	"whenchangesth/internal/rules"
+
+	"gopkg.in/yaml.v3"
+)
+
+// EnvPrefix 是环境变量覆盖配置项时使用的前缀，例如 JIRAHOOK_REDIS_ADDR
+const EnvPrefix = "JIRAHOOK"
+
+// Config 是整个配置文件的结构，启动时加载一次后向下传递
+type Config struct {
+	Server    ServerConfig          `yaml:"server"`
+	Webhook   WebhookConfig         `yaml:"webhook"`
+	Jira      JiraConfig            `yaml:"jira"`
+	DingTalk  DingBotStr            `yaml:"dingtalk"`
+	Robots    map[string]DingBotStr `yaml:"robots"`
+	Routes    []RouteConfig         `yaml:"routes"`
+	Rules     []rules.Rule          `yaml:"rules"`
+	Templates TemplatesConfig       `yaml:"templates"`
+	Redis     RedisConfig           `yaml:"redis"`
+	MySQL     MySQLConfig           `yaml:"mysql"`
+	Phone     PhoneConfig           `yaml:"phone"`
+	Timesheet TimesheetConfig       `yaml:"timesheet"`
+	Sprints   SprintConfig          `yaml:"sprints"`
+	Retry     RetryConfig           `yaml:"retry"`
+	RateLimit RateLimitConfig       `yaml:"rate_limit"`
+	Admin     AdminConfig           `yaml:"admin"`
+	Archive   ArchiveConfig         `yaml:"archive"`
+}
+
+// ServerConfig 定义 HTTP 服务配置部分
+type ServerConfig struct {
+	Addr string `yaml:"addr"`
+	// ShutdownTimeout 是退出时等待进行中的请求、发送和 MySQL 写入的最长时间
+	ShutdownTimeout time.Duration `yaml:"shutdown_timeout"`
+	// Workers 是处理 webhook 的 worker 数量，QueueSize 是等待处理的 webhook 上限，队列满时返回 503
+	Workers   int `yaml:"workers"`
+	QueueSize int `yaml:"queue_size"`
+}
+
+// TemplatesConfig 定义消息模板配置部分，Dir 为空时只使用内置模板
+type TemplatesConfig struct {
+	Dir string `yaml:"dir"`
+}
+
+// defaultConfig 返回填充了默认值的配置
+func defaultConfig() *Config {
+	return &Config{
+		Server: ServerConfig{
+			Addr:            ":4165",
+			ShutdownTimeout: 30 * time.Second,
+			Workers:         8,
+			QueueSize:       1000,
+		},
+		Webhook: WebhookConfig{
+			TokenParam:  "token",
+			TokenHeader: "X-Jirahook-Token",
+			DedupTTL:    24 * time.Hour,
+		},
+		Jira:  JiraConfig{LinkStyle: LinkStyleBrowser},
+		Redis: RedisConfig{Port: "6379"},
+		MySQL: MySQLConfig{
+			Port:            3306,
+			MaxOpenConns:    10,
+			MaxIdleConns:    5,
+			ConnMaxLifetime: 30 * time.Minute,
+			ConnMaxIdleTime: 5 * time.Minute,
+			AutoMigrate:     true,
+		},
+		Phone: PhoneConfig{ReloadInterval: 5 * time.Second},
+		Timesheet: TimesheetConfig{
+			At:            "18:30",
+			MaxDaily:      12 * time.Hour,
+			BackdateLimit: 7 * 24 * time.Hour,
+		},
+		Retry: RetryConfig{
+			MaxAttempts: 6,
+			BaseDelay:   10 * time.Second,
+			MaxDelay:    10 * time.Minute,
+		},
+		RateLimit: RateLimitConfig{PerMinute: 15, Burst: 5},
+		Archive:   ArchiveConfig{Retention: 30 * 24 * time.Hour},
+	}
+}
+
+// Load 依次叠加默认值、YAML 配置文件和 JIRAHOOK_* 环境变量，返回最终配置
+// filePath 为空时只使用默认值和环境变量
+func Load(filePath string) (*Config, error) {
+	cfg := defaultConfig()
+
+	if filePath != "" {
+		data, err := os.ReadFile(filePath)
+		if err != nil {
+			return nil, fmt.Errorf("读取配置文件失败: %v", err)
+		}
+		if err := yaml.Unmarshal(data, cfg); err != nil {
+			return nil, fmt.Errorf("解析配置文件失败: %v", err)
+		}
+	}
+
+	var problems ValidationErrors
+	applyEnv(reflect.ValueOf(cfg).Elem(), "", &problems)
+	if len(problems) > 0 {
+		return nil, problems
+	}
+
+	return cfg, nil
+}
+
+// applyEnv 按 yaml 标签递归遍历配置结构，用同名环境变量覆盖对应字段
+func applyEnv(v reflect.Value, path string, problems *ValidationErrors) {
+	t := v.Type()
+	for i := 0; i < t.NumField(); i++ {
+		field := t.Field(i)
+		name := strings.Split(field.Tag.Get("yaml"), ",")[0]
+		if name == "" || name == "-" || !field.IsExported() {
+			continue
+		}
+		key := name
+		if path != "" {
+			key = path + "." + name
+		}
+
+		fv := v.Field(i)
+		if fv.Kind() == reflect.Struct {
+			applyEnv(fv, key, problems)
+			continue
+		}
+
+		envName := EnvName(key)
+		raw, ok := os.LookupEnv(envName)
+		if !ok {
+			continue
+		}
+		if err := setFromString(fv, raw); err != nil {
+			problems.add(key, fmt.Sprintf("环境变量 %s 无效: %v", envName, err))
+		}
+	}
+}
+
+// EnvName 返回配置项 key（如 redis.addr）对应的环境变量名（如 JIRAHOOK_REDIS_ADDR）
+func EnvName(key string) string {
+	return EnvPrefix + "_" + strings.ToUpper(strings.ReplaceAll(key, ".", "_"))
+}
+
+// setFromString 将环境变量的字符串值写入标量字段
+func setFromString(fv reflect.Value, raw string) error {
+	switch fv.Kind() {
+	case reflect.String:
+		fv.SetString(raw)
+	case reflect.Bool:
+		b, err := strconv.ParseBool(raw)
+		if err != nil {
+			return err
+		}
+		fv.SetBool(b)
+	case reflect.Int, reflect.Int8, reflect.Int16, reflect.Int32, reflect.Int64:
+		if fv.Type() == reflect.TypeOf(time.Duration(0)) {
+			d, err := time.ParseDuration(raw)
+			if err != nil {
+				return err
+			}
+			fv.SetInt(int64(d))
+			return nil
+		}
+		n, err := strconv.ParseInt(raw, 10, 64)
+		if err != nil {
+			return err
+		}
+		fv.SetInt(n)
+	case reflect.Slice:
+		if fv.Type().Elem().Kind() != reflect.String {
+			return fmt.Errorf("不支持的类型 %s", fv.Type())
+		}
+		var items []string
+		for _, item := range strings.Split(raw, ",") {
+			if item = strings.TrimSpace(item); item != "" {
+				items = append(items, item)
+			}
+		}
+		fv.Set(reflect.ValueOf(items))
+	default:
+		return fmt.Errorf("不支持的类型 %s", fv.Type())
+	}
+	return nil
+}
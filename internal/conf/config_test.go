@@ -0,0 +1,59 @@
+package conf
+
+import (
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+)
+
+func TestLoadEnvOverride(t *testing.T) {
+	filePath := filepath.Join(t.TempDir(), "config.yaml")
+	data := "redis:\n  addr: 10.0.0.1\n  port: \"6380\"\nmysql:\n  port: 3307\n"
+	if err := os.WriteFile(filePath, []byte(data), 0o600); err != nil {
+		t.Fatal(err)
+	}
+	t.Setenv("JIRAHOOK_REDIS_ADDR", "10.0.0.2")
+	t.Setenv("JIRAHOOK_MYSQL_PORT", "3308")
+
+	cfg, err := Load(filePath)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if cfg.Redis.Addr != "10.0.0.2" || cfg.Redis.Port != "6380" {
+		t.Errorf("redis = %+v", cfg.Redis)
+	}
+	if cfg.MySQL.Port != 3308 {
+		t.Errorf("mysql.port = %d", cfg.MySQL.Port)
+	}
+	if cfg.Server.Addr != ":4165" {
+		t.Errorf("server.addr = %q", cfg.Server.Addr)
+	}
+}
+
+func TestLoadInvalidEnv(t *testing.T) {
+	t.Setenv("JIRAHOOK_MYSQL_PORT", "abc")
+	if _, err := Load(""); err == nil || !strings.Contains(err.Error(), "mysql.port") {
+		t.Fatalf("err = %v", err)
+	}
+}
+
+func TestValidateReportsAllProblems(t *testing.T) {
+	cfg := defaultConfig()
+	cfg.Redis.Port = "x"
+
+	err := cfg.Validate()
+	problems, ok := err.(ValidationErrors)
+	if !ok {
+		t.Fatalf("err = %T", err)
+	}
+	want := []string{"jira.base_url", "dingtalk.token", "redis.addr", "redis.port", "mysql.host", "mysql.user", "mysql.database", "phone.file"}
+	if len(problems) != len(want) {
+		t.Fatalf("problems = %v", problems)
+	}
+	for i, key := range want {
+		if problems[i].Key != key {
+			t.Errorf("problems[%d] = %s, want %s", i, problems[i].Key, key)
+		}
+	}
+}
@@ -0,0 +1,19 @@
+package conf
+
+// 链接样式
+const (
+	LinkStyleBrowser         = "browser"
+	LinkStyleDingTalkSidebar = "dingtalk_sidebar"
+)
+
+// JiraConfig 定义生成 Jira 链接所需的配置
+type JiraConfig struct {
+	// BaseURL 是 Jira 站点地址，例如 https://example.atlassian.net
+	BaseURL string `yaml:"base_url"`
+	// ContextPath 是 Jira Server 部署在子路径下时的上下文路径，例如 /jira
+	ContextPath string `yaml:"context_path"`
+	// FromPayload 为 true 时优先从 payload 的 issue.self 推导站点地址
+	FromPayload bool `yaml:"from_payload"`
+	// LinkStyle 为 browser 时在浏览器打开，为 dingtalk_sidebar 时在钉钉侧边栏打开
+	LinkStyle string `yaml:"link_style"`
+}
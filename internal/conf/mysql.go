@@ -1,15 +1,6 @@
 package conf
 
-import (
-	"fmt"
-	"gopkg.in/yaml.v3"
-	"os"
-)
-
-// Config 是整个配置文件的结构
-type Config struct {
-	MySQL MySQLConfig `yaml:"mysql"`
-}
+import "time"
 
 // MySQLConfig 定义 mysql 配置部分
 type MySQLConfig struct {
@@ -18,22 +9,13 @@ type MySQLConfig struct {
 	Host     string `yaml:"host"`
 	Port     int    `yaml:"port"`
 	Database string `yaml:"database"`
-}
-
-func ParseMySQLConfig() (*MySQLConfig, error) {
-	// filePath := "/app-acc/configs/mysql.yaml" // 生产路径
-	filePath := "/home/youxihu/secret/jira_hook/mysql.yaml" // 开发路径
-
-	data, err := os.ReadFile(filePath)
-	if err != nil {
-		return nil, fmt.Errorf("读取 mysql 配置文件失败: %v", err)
-	}
 
-	var config Config
-	err = yaml.Unmarshal(data, &config)
-	if err != nil {
-		return nil, fmt.Errorf("解析 mysql 配置文件失败: %v", err)
-	}
+	// 连接池配置，启动时创建一次，所有请求共用
+	MaxOpenConns    int           `yaml:"max_open_conns"`
+	MaxIdleConns    int           `yaml:"max_idle_conns"`
+	ConnMaxLifetime time.Duration `yaml:"conn_max_lifetime"`
+	ConnMaxIdleTime time.Duration `yaml:"conn_max_idle_time"`
 
-	return &config.MySQL, nil
+	// AutoMigrate 为 true 时启动时执行未应用的数据库迁移，否则需要先运行 migrate 子命令
+	AutoMigrate bool `yaml:"auto_migrate"`
 }
@@ -1,46 +1,268 @@
 package conf
 
 import (
+	"context"
 	"fmt"
 	"gopkg.in/yaml.v3"
+	"log"
 	"os"
+	"os/signal"
+	"slices"
+	"strings"
+	"sync"
+	"syscall"
+	"time"
 )
 
-// ParsePhone 解析 secret.yaml 文件并返回一个 map[string]string
-func ParsePhone() (map[string]string, error) {
+// PhoneConfig 定义通讯录配置部分
+type PhoneConfig struct {
+	File           string        `yaml:"file"`
+	ReloadInterval time.Duration `yaml:"reload_interval"`
+}
+
+// Person 是通讯录中的一个人，同时记录其在 Jira 和钉钉中的身份
+type Person struct {
+	Name          string   `yaml:"name"`
+	Phone         string   `yaml:"phone"`
+	DingUserID    string   `yaml:"ding_user_id"`
+	Email         string   `yaml:"email"`
+	JiraAccountID string   `yaml:"jira_account_id"`
+	JiraKey       string   `yaml:"jira_key"`
+	JiraName      string   `yaml:"jira_name"`
+	Team          string   `yaml:"team"`
+	Aliases       []string `yaml:"aliases"`
+}
 
-	//filePath := "/app-acc/configs/phonenumb.yaml"
-	filePath := "/home/youxihu/secret/jira_hook/phonenumb.yaml"
+// phoneFile 是通讯录文件的结构
+type phoneFile struct {
+	People []Person `yaml:"people"`
+}
 
+// ParsePhone 解析通讯录文件并返回人员列表
+// 兼容旧的「displayName: 手机号」扁平格式
+func ParsePhone(filePath string) ([]Person, error) {
 	// 读取文件内容
 	data, err := os.ReadFile(filePath)
 	if err != nil {
 		return nil, fmt.Errorf("failed to read config file: %v", err)
 	}
 
-	// 解析 YAML 文件为 map[string]string
+	var pf phoneFile
+	if err := yaml.Unmarshal(data, &pf); err == nil && len(pf.People) > 0 {
+		return pf.People, nil
+	}
+
+	// 旧格式: 解析 YAML 文件为 map[string]string
 	var phoneMap map[string]string
 	if err := yaml.Unmarshal(data, &phoneMap); err != nil {
 		return nil, fmt.Errorf("failed to parse YAML: %v", err)
 	}
 
-	return phoneMap, nil
+	people := make([]Person, 0, len(phoneMap))
+	for name, phone := range phoneMap {
+		people = append(people, Person{Name: name, Phone: phone})
+	}
+	return people, nil
+}
+
+// UserRef 是 Jira 用户在各个字段上的标识，用于在通讯录中查找对应的人
+type UserRef struct {
+	AccountID   string
+	Key         string
+	Name        string
+	Email       string
+	DisplayName string
+}
+
+// personIndex 是按不同标识建立的人员索引
+type personIndex struct {
+	byAccountID   map[string]*Person
+	byKey         map[string]*Person
+	byEmail       map[string]*Person
+	byAlias       map[string]*Person
+	byDisplayName map[string]*Person
+}
+
+func newPersonIndex(people []Person) *personIndex {
+	idx := &personIndex{
+		byAccountID:   make(map[string]*Person),
+		byKey:         make(map[string]*Person),
+		byEmail:       make(map[string]*Person),
+		byAlias:       make(map[string]*Person),
+		byDisplayName: make(map[string]*Person),
+	}
+	put := func(m map[string]*Person, key string, p *Person) {
+		if key != "" {
+			m[key] = p
+		}
+	}
+	for i := range people {
+		p := &people[i]
+		put(idx.byAccountID, p.JiraAccountID, p)
+		put(idx.byKey, p.JiraKey, p)
+		put(idx.byKey, p.JiraName, p)
+		put(idx.byEmail, strings.ToLower(p.Email), p)
+		for _, alias := range p.Aliases {
+			put(idx.byAlias, alias, p)
+		}
+		put(idx.byDisplayName, p.Name, p)
+	}
+	return idx
+}
+
+// resolve 依次按 accountId、key/name、email、别名查找，最后才使用 displayName
+func (idx *personIndex) resolve(u UserRef) (*Person, bool) {
+	lookups := []struct {
+		m   map[string]*Person
+		key string
+	}{
+		{idx.byAccountID, u.AccountID},
+		{idx.byKey, u.Key},
+		{idx.byKey, u.Name},
+		{idx.byEmail, strings.ToLower(u.Email)},
+		{idx.byAlias, u.DisplayName},
+		{idx.byAlias, u.Name},
+		{idx.byDisplayName, u.DisplayName},
+	}
+	for _, l := range lookups {
+		if l.key == "" {
+			continue
+		}
+		if p, ok := l.m[l.key]; ok {
+			return p, true
+		}
+	}
+	return nil, false
 }
 
-// GetPhoneNumber 根据 key 查找对应的电话号码
-func GetPhoneNumber(key string) (string, error) {
-	// 解析 YAML 文件
-	phoneMap, err := ParsePhone()
+// PhoneBookStats 记录通讯录的加载情况，用于确认修改是否已生效
+type PhoneBookStats struct {
+	File        string    `json:"file"`
+	Entries     int       `json:"entries"`
+	Reloads     uint64    `json:"reloads"`
+	Failures    uint64    `json:"failures"`
+	LastReload  time.Time `json:"last_reload"`
+	LastAttempt time.Time `json:"last_attempt"`
+	LastError   string    `json:"last_error,omitempty"`
+}
+
+// PhoneBook 是常驻内存的人员通讯录
+// 文件变更或收到 SIGHUP 时自动重新加载，解析失败则保留上一份可用数据
+type PhoneBook struct {
+	filePath string
+
+	mu      sync.RWMutex
+	index   *personIndex
+	people  []Person
+	modTime time.Time
+	size    int64
+	stats   PhoneBookStats
+}
+
+// NewPhoneBook 加载通讯录文件，首次加载失败时返回错误
+func NewPhoneBook(filePath string) (*PhoneBook, error) {
+	pb := &PhoneBook{filePath: filePath}
+	pb.stats.File = filePath
+	if err := pb.Reload(); err != nil {
+		return nil, err
+	}
+	return pb, nil
+}
+
+// Reload 重新读取通讯录文件，失败时保留上一份数据
+func (pb *PhoneBook) Reload() error {
+	info, statErr := os.Stat(pb.filePath)
+	people, err := ParsePhone(pb.filePath)
+
+	pb.mu.Lock()
+	defer pb.mu.Unlock()
+
+	pb.stats.LastAttempt = time.Now()
+	if err == nil && statErr != nil {
+		err = statErr
+	}
+	if statErr == nil {
+		// 失败时也记录文件状态，避免轮询对同一份坏文件反复重试
+		pb.modTime = info.ModTime()
+		pb.size = info.Size()
+	}
 	if err != nil {
-		return "", fmt.Errorf("failed to parse phone config: %v", err)
+		pb.stats.Failures++
+		pb.stats.LastError = err.Error()
+		return err
 	}
 
-	// 查找电话号码
-	phoneNumber, exists := phoneMap[key]
-	if !exists {
-		// 如果未找到，返回空字符串
-		return "", nil
+	pb.index = newPersonIndex(people)
+	pb.people = people
+	pb.stats.Entries = len(people)
+	pb.stats.Reloads++
+	pb.stats.LastReload = pb.stats.LastAttempt
+	pb.stats.LastError = ""
+	return nil
+}
+
+// Resolve 根据 Jira 用户标识查找对应的人
+func (pb *PhoneBook) Resolve(u UserRef) (*Person, bool) {
+	pb.mu.RLock()
+	defer pb.mu.RUnlock()
+
+	return pb.index.resolve(u)
+}
+
+// People 返回通讯录中的全部人员
+func (pb *PhoneBook) People() []Person {
+	pb.mu.RLock()
+	defer pb.mu.RUnlock()
+
+	return slices.Clone(pb.people)
+}
+
+// Stats 返回通讯录加载统计
+func (pb *PhoneBook) Stats() PhoneBookStats {
+	pb.mu.RLock()
+	defer pb.mu.RUnlock()
+	return pb.stats
+}
+
+// changed 判断文件的修改时间或大小是否与上次加载时不同
+func (pb *PhoneBook) changed() bool {
+	info, err := os.Stat(pb.filePath)
+	if err != nil {
+		return false
+	}
+
+	pb.mu.RLock()
+	defer pb.mu.RUnlock()
+	return !info.ModTime().Equal(pb.modTime) || info.Size() != pb.size
+}
+
+// Watch 按 interval 轮询文件修改时间，并在收到 SIGHUP 时重新加载，直到 ctx 结束
+func (pb *PhoneBook) Watch(ctx context.Context, interval time.Duration) {
+	hup := make(chan os.Signal, 1)
+	signal.Notify(hup, syscall.SIGHUP)
+	defer signal.Stop(hup)
+
+	ticker := time.NewTicker(interval)
+	defer ticker.Stop()
+
+	for {
+		select {
+		case <-ctx.Done():
+			return
+		case <-hup:
+			pb.reloadAndLog("SIGHUP")
+		case <-ticker.C:
+			if pb.changed() {
+				pb.reloadAndLog("文件变更")
+			}
+		}
 	}
+}
 
-	return phoneNumber, nil
+func (pb *PhoneBook) reloadAndLog(reason string) {
+	if err := pb.Reload(); err != nil {
+		log.Printf("⚠️ 通讯录重新加载失败（%s），继续使用上一份数据: %v", reason, err)
+		return
+	}
+	log.Printf("通讯录已重新加载（%s）: %d 条", reason, pb.Stats().Entries)
 }
@@ -0,0 +1,65 @@
+package conf
+
+import (
+	"os"
+	"path/filepath"
+	"testing"
+)
+
+func TestPhoneBookKeepsLastGoodCopy(t *testing.T) {
+	filePath := filepath.Join(t.TempDir(), "phonenumb.yaml")
+	if err := os.WriteFile(filePath, []byte("张三: \"13800000000\"\n"), 0o600); err != nil {
+		t.Fatal(err)
+	}
+
+	pb, err := NewPhoneBook(filePath)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if p, _ := pb.Resolve(UserRef{DisplayName: "张三"}); p == nil || p.Phone != "13800000000" {
+		t.Fatalf("person = %+v", p)
+	}
+
+	if err := os.WriteFile(filePath, []byte("张三: [broken\n"), 0o600); err != nil {
+		t.Fatal(err)
+	}
+	if err := pb.Reload(); err == nil {
+		t.Fatal("expected parse error")
+	}
+	if p, _ := pb.Resolve(UserRef{DisplayName: "张三"}); p == nil || p.Phone != "13800000000" {
+		t.Fatalf("person after failed reload = %+v", p)
+	}
+
+	stats := pb.Stats()
+	if stats.Reloads != 1 || stats.Failures != 1 || stats.LastError == "" {
+		t.Fatalf("stats = %+v", stats)
+	}
+}
+
+func TestResolveOrder(t *testing.T) {
+	idx := newPersonIndex([]Person{
+		{Name: "张三", Phone: "1", JiraAccountID: "acc-1"},
+		{Name: "李四", Phone: "2", JiraKey: "lisi", Email: "LiSi@example.com", Aliases: []string{"老李"}},
+		{Name: "王五", Phone: "3"},
+	})
+
+	cases := []struct {
+		ref  UserRef
+		want string
+	}{
+		{UserRef{AccountID: "acc-1", DisplayName: "王五"}, "1"},
+		{UserRef{Key: "lisi", DisplayName: "王五"}, "2"},
+		{UserRef{Email: "lisi@example.com"}, "2"},
+		{UserRef{DisplayName: "老李"}, "2"},
+		{UserRef{AccountID: "unknown", DisplayName: "王五"}, "3"},
+	}
+	for _, c := range cases {
+		p, ok := idx.resolve(c.ref)
+		if !ok || p.Phone != c.want {
+			t.Errorf("resolve(%+v) = %+v, want phone %s", c.ref, p, c.want)
+		}
+	}
+	if _, ok := idx.resolve(UserRef{DisplayName: "赵六"}); ok {
+		t.Error("unexpected match for unknown user")
+	}
+}
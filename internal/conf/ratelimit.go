@@ -0,0 +1,10 @@
+package conf
+
+// RateLimitConfig 定义每个钉钉机器人的发送限流配置部分
+// 钉钉自定义机器人每分钟最多接受 20 条消息，超过后会限流一段时间，一分钟内最多发送 PerMinute+Burst 条
+type RateLimitConfig struct {
+	// PerMinute 是每分钟补充的发送次数
+	PerMinute int `yaml:"per_minute"`
+	// Burst 是空闲后最多可以连续发送的次数
+	Burst int `yaml:"burst"`
+}
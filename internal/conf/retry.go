@@ -0,0 +1,20 @@
+package conf
+
+import "time"
+
+// RetryConfig 定义钉钉发送失败后的重试配置部分
+type RetryConfig struct {
+	// MaxAttempts 是包括首次发送在内的最多尝试次数，用尽后转入死信表
+	MaxAttempts int `yaml:"max_attempts"`
+	// BaseDelay 是首次重试的等待时间，之后每次翻倍
+	BaseDelay time.Duration `yaml:"base_delay"`
+	// MaxDelay 是单次等待时间的上限
+	MaxDelay time.Duration `yaml:"max_delay"`
+}
+
+// AdminConfig 定义管理接口配置部分，Token 为空时管理接口不可用
+// ReadToken 只能访问事件历史和发布说明等只读查询接口，Token 也可以访问这些接口，两者都为空时只读接口不可用
+type AdminConfig struct {
+	Token     string `yaml:"token"`
+	ReadToken string `yaml:"read_token"`
+}
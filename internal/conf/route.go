@@ -0,0 +1,46 @@
+package conf
+
+import (
+	"sort"
+	"whenchangesth/internal/rules"
+)
+
+// DefaultRobot 是 dingtalk 配置段对应的机器人名称，没有路由命中时使用
+const DefaultRobot = "default"
+
+// RouteConfig 将满足条件的事件路由到指定机器人
+// 同一字段内的多个值任一命中即可，不同字段之间需要同时命中，未配置的字段不参与匹配
+type RouteConfig struct {
+	Projects   []string `yaml:"projects"`
+	IssueTypes []string `yaml:"issue_types"`
+	Components []string `yaml:"components"`
+	Labels     []string `yaml:"labels"`
+	Robots     []string `yaml:"robots"`
+}
+
+// Robot 按名称返回机器人配置，default 对应 dingtalk 配置段
+func (c *Config) Robot(name string) (DingBotStr, bool) {
+	if name == DefaultRobot {
+		return c.DingTalk, true
+	}
+	robot, ok := c.Robots[name]
+	return robot, ok
+}
+
+// RobotNames 返回全部机器人名称，default 排在最前
+func (c *Config) RobotNames() []string {
+	names := make([]string, 0, len(c.Robots)+1)
+	for name := range c.Robots {
+		names = append(names, name)
+	}
+	sort.Strings(names)
+	return append([]string{DefaultRobot}, names...)
+}
+
+// EffectiveRules 返回生效的通知规则，未配置时使用内置规则
+func (c *Config) EffectiveRules() []rules.Rule {
+	if len(c.Rules) == 0 {
+		return rules.Defaults()
+	}
+	return c.Rules
+}
@@ -0,0 +1,20 @@
+package conf
+
+// SprintConfig 定义 Sprint 开始和关闭通知的配置部分
+type SprintConfig struct {
+	// Robots 是默认接收 Sprint 通知的机器人，为空时使用 default
+	Robots []string `yaml:"robots"`
+	// Boards 按看板 ID 指定机器人，优先于 Robots
+	Boards map[int][]string `yaml:"boards"`
+}
+
+// SprintRobots 返回某个看板的 Sprint 通知机器人
+func (c *Config) SprintRobots(boardID int) []string {
+	if robots, ok := c.Sprints.Boards[boardID]; ok && len(robots) > 0 {
+		return robots
+	}
+	if len(c.Sprints.Robots) > 0 {
+		return c.Sprints.Robots
+	}
+	return []string{DefaultRobot}
+}
@@ -0,0 +1,16 @@
+package conf
+
+import "time"
+
+// TimesheetConfig 定义每日工时汇总配置部分
+type TimesheetConfig struct {
+	Enabled bool `yaml:"enabled"`
+	// At 是每天发送汇总的时间，格式为 15:04
+	At string `yaml:"at"`
+	// Teams 是团队到机器人的映射，团队取自通讯录中的 team 字段
+	Teams map[string]string `yaml:"teams"`
+	// MaxDaily 是单人单日工时上限，超出时标记为可疑
+	MaxDaily time.Duration `yaml:"max_daily"`
+	// BackdateLimit 是允许补录的最长时间，开始时间早于记录时间超过该值时标记为可疑
+	BackdateLimit time.Duration `yaml:"backdate_limit"`
+}
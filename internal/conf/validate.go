@@ -0,0 +1,237 @@
+package conf
+
+import (
+	"fmt"
+	"net/url"
+	"slices"
+	"sort"
+	"strconv"
+	"strings"
+	"time"
+	"whenchangesth/internal/rules"
+)
+
+// FieldError 描述单个配置项的问题
+type FieldError struct {
+	Key string
+	Msg string
+}
+
+// ValidationErrors 汇总配置中的全部问题，便于启动时一次性输出
+type ValidationErrors []FieldError
+
+func (ve ValidationErrors) Error() string {
+	lines := make([]string, 0, len(ve))
+	for _, fe := range ve {
+		lines = append(lines, fmt.Sprintf("%s: %s", fe.Key, fe.Msg))
+	}
+	return strings.Join(lines, "\n")
+}
+
+func (ve *ValidationErrors) add(key, msg string) {
+	*ve = append(*ve, FieldError{Key: key, Msg: msg})
+}
+
+// Validate 校验配置，返回 ValidationErrors 列出所有问题
+func (c *Config) Validate() error {
+	var problems ValidationErrors
+
+	if c.Server.Addr == "" {
+		problems.add("server.addr", "不能为空")
+	}
+	if c.Server.ShutdownTimeout <= 0 {
+		problems.add("server.shutdown_timeout", "必须大于 0")
+	}
+	if c.Server.Workers < 1 {
+		problems.add("server.workers", "不能小于 1")
+	}
+	if c.Server.QueueSize < 1 {
+		problems.add("server.queue_size", "不能小于 1")
+	}
+
+	if c.Webhook.Token != "" && c.Webhook.TokenParam == "" && c.Webhook.TokenHeader == "" {
+		problems.add("webhook.token", "配置了 token 时 token_param 和 token_header 至少需要一个")
+	}
+	if c.Webhook.ReplayWindow < 0 {
+		problems.add("webhook.replay_window", "不能为负数")
+	}
+	if c.Webhook.DedupTTL < 0 {
+		problems.add("webhook.dedup_ttl", "不能为负数")
+	}
+
+	if c.Jira.BaseURL == "" && !c.Jira.FromPayload {
+		problems.add("jira.base_url", "未开启 from_payload 时不能为空")
+	}
+	if c.Jira.BaseURL != "" {
+		if u, err := url.Parse(c.Jira.BaseURL); err != nil || u.Scheme == "" || u.Host == "" {
+			problems.add("jira.base_url", fmt.Sprintf("不是合法的 URL: %q", c.Jira.BaseURL))
+		}
+	}
+	if c.Jira.ContextPath != "" && !strings.HasPrefix(c.Jira.ContextPath, "/") {
+		problems.add("jira.context_path", "必须以 / 开头")
+	}
+	if c.Jira.LinkStyle != LinkStyleBrowser && c.Jira.LinkStyle != LinkStyleDingTalkSidebar {
+		problems.add("jira.link_style", fmt.Sprintf("可选值: %s, %s", LinkStyleBrowser, LinkStyleDingTalkSidebar))
+	}
+
+	if c.DingTalk.Token == "" {
+		problems.add("dingtalk.token", "不能为空")
+	}
+
+	if _, ok := c.Robots[DefaultRobot]; ok {
+		problems.add("robots."+DefaultRobot, "default 为保留名称，对应 dingtalk 配置段")
+	}
+	names := make([]string, 0, len(c.Robots))
+	for name := range c.Robots {
+		names = append(names, name)
+	}
+	sort.Strings(names)
+	for _, name := range names {
+		// 机器人名称是 Redis Key 的一部分
+		if strings.Contains(name, ":") {
+			problems.add("robots."+name, "名称不能包含冒号")
+		}
+		if c.Robots[name].Token == "" {
+			problems.add(fmt.Sprintf("robots.%s.token", name), "不能为空")
+		}
+	}
+	for i, route := range c.Routes {
+		if len(route.Robots) == 0 {
+			problems.add(fmt.Sprintf("routes[%d].robots", i), "不能为空")
+		}
+		for _, name := range route.Robots {
+			if _, ok := c.Robot(name); !ok {
+				problems.add(fmt.Sprintf("routes[%d].robots", i), fmt.Sprintf("未定义的机器人 %q", name))
+			}
+		}
+	}
+
+	for i, rule := range c.Rules {
+		key := fmt.Sprintf("rules[%d]", i)
+		if rule.Name == "" {
+			problems.add(key+".name", "不能为空")
+		}
+		for _, name := range rule.Actions.Notify {
+			if _, ok := c.Robot(name); !ok {
+				problems.add(key+".actions.notify", fmt.Sprintf("未定义的机器人 %q", name))
+			}
+		}
+		for _, role := range rule.Actions.Mention {
+			if !slices.Contains(rules.Roles, role) {
+				problems.add(key+".actions.mention", fmt.Sprintf("未知角色 %q，可选值: %s", role, strings.Join(rules.Roles, ", ")))
+			}
+		}
+		if rule.Actions.Skip && (len(rule.Actions.Notify) > 0 || len(rule.Actions.Mention) > 0 || rule.Actions.Immediate) {
+			problems.add(key+".actions", "skip 不能与其他动作同时使用")
+		}
+	}
+
+	if c.Redis.Addr == "" {
+		problems.add("redis.addr", "不能为空")
+	}
+	if _, err := strconv.Atoi(c.Redis.Port); err != nil {
+		problems.add("redis.port", fmt.Sprintf("不是合法端口: %q", c.Redis.Port))
+	}
+	if c.Redis.DB < 0 {
+		problems.add("redis.db", "不能为负数")
+	}
+
+	if c.MySQL.Host == "" {
+		problems.add("mysql.host", "不能为空")
+	}
+	if c.MySQL.Port <= 0 || c.MySQL.Port > 65535 {
+		problems.add("mysql.port", fmt.Sprintf("不是合法端口: %d", c.MySQL.Port))
+	}
+	if c.MySQL.User == "" {
+		problems.add("mysql.user", "不能为空")
+	}
+	if c.MySQL.Database == "" {
+		problems.add("mysql.database", "不能为空")
+	}
+	if c.MySQL.MaxOpenConns < 1 {
+		problems.add("mysql.max_open_conns", "不能小于 1")
+	}
+	if c.MySQL.MaxIdleConns < 0 || c.MySQL.MaxIdleConns > c.MySQL.MaxOpenConns {
+		problems.add("mysql.max_idle_conns", "应在 0 到 max_open_conns 之间")
+	}
+	if c.MySQL.ConnMaxLifetime < 0 {
+		problems.add("mysql.conn_max_lifetime", "不能为负数")
+	}
+	if c.MySQL.ConnMaxIdleTime < 0 {
+		problems.add("mysql.conn_max_idle_time", "不能为负数")
+	}
+
+	if c.Phone.File == "" {
+		problems.add("phone.file", "不能为空")
+	}
+	if c.Phone.ReloadInterval <= 0 {
+		problems.add("phone.reload_interval", "必须大于 0")
+	}
+
+	if c.Retry.MaxAttempts < 1 {
+		problems.add("retry.max_attempts", "不能小于 1")
+	}
+	if c.Retry.BaseDelay <= 0 {
+		problems.add("retry.base_delay", "必须大于 0")
+	}
+	if c.Retry.MaxDelay < c.Retry.BaseDelay {
+		problems.add("retry.max_delay", "不能小于 base_delay")
+	}
+	if c.RateLimit.PerMinute < 1 {
+		problems.add("rate_limit.per_minute", "不能小于 1")
+	}
+	if c.RateLimit.Burst < 1 {
+		problems.add("rate_limit.burst", "不能小于 1")
+	}
+	if c.Archive.Retention < 0 {
+		problems.add("archive.retention", "不能为负数")
+	}
+
+	for i, name := range c.Sprints.Robots {
+		if _, ok := c.Robot(name); !ok {
+			problems.add(fmt.Sprintf("sprints.robots[%d]", i), fmt.Sprintf("未定义的机器人 %q", name))
+		}
+	}
+	boards := make([]int, 0, len(c.Sprints.Boards))
+	for board := range c.Sprints.Boards {
+		boards = append(boards, board)
+	}
+	sort.Ints(boards)
+	for _, board := range boards {
+		for i, name := range c.Sprints.Boards[board] {
+			if _, ok := c.Robot(name); !ok {
+				problems.add(fmt.Sprintf("sprints.boards.%d[%d]", board, i), fmt.Sprintf("未定义的机器人 %q", name))
+			}
+		}
+	}
+
+	if c.Timesheet.Enabled {
+		if _, err := time.Parse("15:04", c.Timesheet.At); err != nil {
+			problems.add("timesheet.at", fmt.Sprintf("格式应为 15:04: %q", c.Timesheet.At))
+		}
+		if len(c.Timesheet.Teams) == 0 {
+			problems.add("timesheet.teams", "开启工时汇总时不能为空")
+		}
+		teams := make([]string, 0, len(c.Timesheet.Teams))
+		for team := range c.Timesheet.Teams {
+			teams = append(teams, team)
+		}
+		sort.Strings(teams)
+		for _, team := range teams {
+			if _, ok := c.Robot(c.Timesheet.Teams[team]); !ok {
+				problems.add("timesheet.teams."+team, fmt.Sprintf("未定义的机器人 %q", c.Timesheet.Teams[team]))
+			}
+		}
+		if c.Timesheet.MaxDaily <= 0 {
+			problems.add("timesheet.max_daily", "必须大于 0")
+		}
+		if c.Timesheet.BackdateLimit <= 0 {
+			problems.add("timesheet.backdate_limit", "必须大于 0")
+		}
+	}
+
+	if len(problems) > 0 {
+		return problems
+	}
+	return nil
+}
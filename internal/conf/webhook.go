@@ -0,0 +1,17 @@
+package conf
+
+import "time"
+
+// WebhookConfig 定义 Jira webhook 的来源校验配置，未配置的校验项不生效
+type WebhookConfig struct {
+	// Secret 用于校验 Jira Cloud 的 X-Hub-Signature（HMAC-SHA256）
+	Secret string `yaml:"secret"`
+	// Token 是 Jira Server 通过查询参数或请求头携带的共享密钥
+	Token       string `yaml:"token"`
+	TokenParam  string `yaml:"token_param"`
+	TokenHeader string `yaml:"token_header"`
+	// ReplayWindow 是 payload 中 timestamp 与当前时间允许的最大偏差
+	ReplayWindow time.Duration `yaml:"replay_window"`
+	// DedupTTL 是已处理 webhook 的幂等键保留时间，0 表示不去重
+	DedupTTL time.Duration `yaml:"dedup_ttl"`
+}
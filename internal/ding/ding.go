@@ -0,0 +1,105 @@
+package ding
+
+import (
+	"bytes"
+	"crypto/hmac"
+	"crypto/sha256"
+	"encoding/base64"
+	"encoding/json"
+	"fmt"
+	"io"
+	"net/http"
+	"net/url"
+	"strconv"
+	"time"
+)
+
+// MaxTextBytes 是钉钉 markdown 消息正文的长度上限
+const MaxTextBytes = 20000
+
+// Message 是一条钉钉 markdown 消息
+type Message struct {
+	Title     string
+	Text      string
+	AtMobiles []string
+	AtUserIds []string
+	IsAtAll   bool
+}
+
+// markdownMessage 定义钉钉机器人接口的请求体
+type markdownMessage struct {
+	MsgType  string `json:"msgtype"`
+	Markdown struct {
+		Title string `json:"title"`
+		Text  string `json:"text"`
+	} `json:"markdown"`
+	At struct {
+		AtMobiles []string `json:"atMobiles,omitempty"`
+		AtUserIds []string `json:"atUserIds,omitempty"`
+		IsAtAll   bool     `json:"isAtAll"`
+	} `json:"at"`
+}
+
+// APIError 是钉钉接口返回的业务错误
+type APIError struct {
+	Code int    `json:"errcode"`
+	Msg  string `json:"errmsg"`
+}
+
+func (e *APIError) Error() string {
+	return fmt.Sprintf("dingtalk errcode %d: %s", e.Code, e.Msg)
+}
+
+var httpClient = &http.Client{Timeout: 10 * time.Second}
+
+// Send 通过机器人 webhook 发送 markdown 消息，secret 不为空时进行加签
+func Send(webhookURL, secret string, msg Message) error {
+	// 构造消息体
+	var body markdownMessage
+	body.MsgType = "markdown"
+	body.Markdown.Title = msg.Title
+	body.Markdown.Text = msg.Text
+	body.At.AtMobiles = msg.AtMobiles
+	body.At.AtUserIds = msg.AtUserIds
+	body.At.IsAtAll = msg.IsAtAll
+
+	payload, err := json.Marshal(body)
+	if err != nil {
+		return fmt.Errorf("failed to marshal message: %v", err)
+	}
+
+	// 如果有密钥，则进行加签
+	if secret != "" {
+		timestamp := strconv.FormatInt(time.Now().UnixNano()/1e6, 10)
+		webhookURL += "&timestamp=" + timestamp + "&sign=" + url.QueryEscape(sign(timestamp, secret))
+	}
+
+	resp, err := httpClient.Post(webhookURL, "application/json", bytes.NewBuffer(payload))
+	if err != nil {
+		return fmt.Errorf("failed to send request: %v", err)
+	}
+	defer resp.Body.Close()
+
+	respBody, _ := io.ReadAll(resp.Body)
+	if resp.StatusCode != http.StatusOK {
+		return fmt.Errorf("unexpected status code: %d, body: %s", resp.StatusCode, string(respBody))
+	}
+
+	// 钉钉在业务失败时同样返回 200，需要检查 errcode
+	var apiErr APIError
+	if err := json.Unmarshal(respBody, &apiErr); err != nil {
+		return fmt.Errorf("failed to parse response: %v, body: %s", err, string(respBody))
+	}
+	if apiErr.Code != 0 {
+		return &apiErr
+	}
+	return nil
+}
+
+// sign 生成加签签名
+func sign(timestamp, secret string) string {
+	stringToSign := timestamp + "\n" + secret
+	h := hmac.New(sha256.New, []byte(secret))
+	h.Write([]byte(stringToSign))
+	return base64.StdEncoding.EncodeToString(h.Sum(nil))
+}
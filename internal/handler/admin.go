@@ -0,0 +1,176 @@
+package handler
+
+import (
+	"crypto/subtle"
+	"database/sql"
+	"encoding/json"
+	"errors"
+	"log"
+	"net/http"
+	"strconv"
+	"strings"
+	"time"
+	"whenchangesth/internal/conf"
+
+	"github.com/gin-gonic/gin"
+)
+
+// AdminAuth 校验管理接口的 Authorization: Bearer <token>，未配置 token 时拒绝所有请求
+func AdminAuth(cfg conf.AdminConfig) gin.HandlerFunc {
+	return bearerAuth("Admin API is disabled", cfg.Token)
+}
+
+// ReadAuth 校验只读查询接口的令牌，admin.read_token 和 admin.token 均可访问，都未配置时拒绝所有请求
+func ReadAuth(cfg conf.AdminConfig) gin.HandlerFunc {
+	return bearerAuth("Read API is disabled", cfg.ReadToken, cfg.Token)
+}
+
+// bearerAuth 校验 Authorization: Bearer <token> 是否为 tokens 中任一非空令牌
+func bearerAuth(disabled string, tokens ...string) gin.HandlerFunc {
+	return func(c *gin.Context) {
+		configured := false
+		token, _ := strings.CutPrefix(c.GetHeader("Authorization"), "Bearer ")
+		for _, want := range tokens {
+			if want == "" {
+				continue
+			}
+			configured = true
+			if subtle.ConstantTimeCompare([]byte(token), []byte(want)) == 1 {
+				c.Next()
+				return
+			}
+		}
+		if !configured {
+			c.AbortWithStatusJSON(http.StatusForbidden, gin.H{"error": disabled})
+			return
+		}
+		log.Printf("接口鉴权失败: %s %s", c.ClientIP(), c.Request.URL.Path)
+		c.AbortWithStatusJSON(http.StatusUnauthorized, gin.H{"error": "Unauthorized"})
+	}
+}
+
+// deadLetter 是死信表中的一条消息
+type deadLetter struct {
+	ID        int64      `json:"id"`
+	Robot     string     `json:"robot"`
+	Content   string     `json:"content"`
+	Mentions  []string   `json:"mentions"`
+	Attempts  int        `json:"attempts"`
+	LastError string     `json:"last_error"`
+	CreatedAt time.Time  `json:"created_at"`
+	DeadAt    time.Time  `json:"dead_at"`
+	ResentAt  *time.Time `json:"resent_at,omitempty"`
+}
+
+const deadLetterColumns = `id, robot, content, mentions, attempts, last_error, created_at, dead_at, resent_at`
+
+// scanDeadLetter 读取一行死信
+func scanDeadLetter(row interface{ Scan(...interface{}) error }) (deadLetter, error) {
+	var d deadLetter
+	var mentions string
+	var resentAt sql.NullTime
+	if err := row.Scan(&d.ID, &d.Robot, &d.Content, &mentions, &d.Attempts, &d.LastError,
+		&d.CreatedAt, &d.DeadAt, &resentAt); err != nil {
+		return d, err
+	}
+	_ = json.Unmarshal([]byte(mentions), &d.Mentions)
+	if resentAt.Valid {
+		d.ResentAt = &resentAt.Time
+	}
+	return d, nil
+}
+
+// DeadLettersHandler 列出死信，默认只列出未重新发送的消息，?all=true 时列出全部
+func DeadLettersHandler(c *gin.Context) {
+	limit, err := strconv.Atoi(c.DefaultQuery("limit", "50"))
+	if err != nil || limit <= 0 || limit > 500 {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "limit must be between 1 and 500"})
+		return
+	}
+	query := `SELECT ` + deadLetterColumns + ` FROM jirahook_dead_letter`
+	if c.Query("all") != "true" {
+		query += ` WHERE resent_at IS NULL`
+	}
+	query += ` ORDER BY id DESC LIMIT ?`
+
+	letters := []deadLetter{}
+	err = withDB(func(db *sql.DB) error {
+		rows, err := db.Query(query, limit)
+		if err != nil {
+			return err
+		}
+		defer rows.Close()
+		for rows.Next() {
+			d, err := scanDeadLetter(rows)
+			if err != nil {
+				return err
+			}
+			letters = append(letters, d)
+		}
+		return rows.Err()
+	})
+	if err != nil {
+		log.Printf("Failed to list dead letters: %v", err)
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to list dead letters"})
+		return
+	}
+	c.JSON(http.StatusOK, gin.H{"dead_letters": letters})
+}
+
+// ResendDeadLetterHandler 立即重新发送一条死信，成功后记录重新发送时间
+func ResendDeadLetterHandler(c *gin.Context) {
+	id, err := strconv.ParseInt(c.Param("id"), 10, 64)
+	if err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "Invalid id"})
+		return
+	}
+
+	var d deadLetter
+	err = withDB(func(db *sql.DB) error {
+		d, err = scanDeadLetter(db.QueryRow(`SELECT `+deadLetterColumns+` FROM jirahook_dead_letter WHERE id = ?`, id))
+		return err
+	})
+	if errors.Is(err, sql.ErrNoRows) {
+		c.JSON(http.StatusNotFound, gin.H{"error": "Dead letter not found"})
+		return
+	}
+	if err != nil {
+		log.Printf("Failed to load dead letter %d: %v", id, err)
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to load dead letter"})
+		return
+	}
+
+	mentions := make([]mention, 0, len(d.Mentions))
+	for _, t := range d.Mentions {
+		mentions = append(mentions, parseMention(t))
+	}
+	// 同样经过限流，额度用尽时等待下一个令牌；请求中途断开时仍会在发送成功后记录
+	result := make(chan error, 1)
+	submitNotification(d.Robot, d.Content, mentions, func(err error) {
+		if err == nil {
+			markDeadLetterResent(id)
+		}
+		result <- err
+	})
+	select {
+	case err := <-result:
+		if err != nil {
+			c.JSON(http.StatusBadGateway, gin.H{"error": err.Error()})
+			return
+		}
+		c.JSON(http.StatusOK, gin.H{"message": "Resent"})
+	case <-c.Request.Context().Done():
+	}
+}
+
+// markDeadLetterResent 记录死信的重新发送时间
+func markDeadLetterResent(id int64) {
+	err := writeDB("jirahook_dead_letter", func(db *sql.DB) error {
+		_, err := db.Exec(`UPDATE jirahook_dead_letter SET resent_at = ? WHERE id = ?`, time.Now(), id)
+		return err
+	})
+	if err != nil {
+		// 消息已经发出，只记录标记失败
+		log.Printf("Failed to mark dead letter %d as resent: %v", id, err)
+	}
+}
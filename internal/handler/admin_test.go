@@ -0,0 +1,50 @@
+package handler
+
+import (
+	"net/http"
+	"net/http/httptest"
+	"testing"
+	"whenchangesth/internal/conf"
+
+	"github.com/gin-gonic/gin"
+)
+
+func TestReadAuth(t *testing.T) {
+	gin.SetMode(gin.TestMode)
+	cases := []struct {
+		name  string
+		cfg   conf.AdminConfig
+		token string
+		code  int
+	}{
+		{"disabled", conf.AdminConfig{}, "", http.StatusForbidden},
+		{"read token", conf.AdminConfig{ReadToken: "r"}, "r", http.StatusOK},
+		{"admin token", conf.AdminConfig{Token: "a", ReadToken: "r"}, "a", http.StatusOK},
+		{"missing", conf.AdminConfig{ReadToken: "r"}, "", http.StatusUnauthorized},
+		{"wrong", conf.AdminConfig{Token: "a", ReadToken: "r"}, "x", http.StatusUnauthorized},
+	}
+	for _, c := range cases {
+		r := gin.New()
+		r.GET("/events", ReadAuth(c.cfg), func(c *gin.Context) { c.Status(http.StatusOK) })
+		req := httptest.NewRequest("GET", "/events", nil)
+		if c.token != "" {
+			req.Header.Set("Authorization", "Bearer "+c.token)
+		}
+		w := httptest.NewRecorder()
+		r.ServeHTTP(w, req)
+		if w.Code != c.code {
+			t.Errorf("%s: status = %d, want %d", c.name, w.Code, c.code)
+		}
+	}
+
+	// 只读令牌不能访问管理接口
+	r := gin.New()
+	r.GET("/admin/dead-letters", AdminAuth(conf.AdminConfig{Token: "a", ReadToken: "r"}), func(c *gin.Context) { c.Status(http.StatusOK) })
+	req := httptest.NewRequest("GET", "/admin/dead-letters", nil)
+	req.Header.Set("Authorization", "Bearer r")
+	w := httptest.NewRecorder()
+	r.ServeHTTP(w, req)
+	if w.Code != http.StatusUnauthorized {
+		t.Errorf("read token on admin API: status = %d", w.Code)
+	}
+}
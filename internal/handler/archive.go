@@ -0,0 +1,229 @@
+package handler
+
+import (
+	"bytes"
+	"compress/gzip"
+	"context"
+	"database/sql"
+	"encoding/json"
+	"fmt"
+	"io"
+	"log"
+	"net/http"
+	"strings"
+	"time"
+)
+
+const (
+	archiveCleanupInterval = time.Hour // 清理过期存档的间隔
+	archiveCleanupBatch    = 1000      // 每次删除的最多行数，避免长时间锁表
+)
+
+// archivedWebhook 是一条存档的 webhook 原始请求
+type archivedWebhook struct {
+	ID         int64
+	ReceivedAt time.Time
+	Event      string
+	Action     string
+	IssueKey   string
+	Headers    map[string]string
+	Body       []byte
+}
+
+// newArchivedWebhook 记录请求头和请求体，不保存携带凭据的请求头
+func newArchivedWebhook(header http.Header, body []byte, received time.Time) *archivedWebhook {
+	a := &archivedWebhook{ReceivedAt: received, Headers: make(map[string]string), Body: body}
+	a.Event, a.Action = webhookLabels(body)
+
+	var hook struct {
+		Issue struct {
+			Key string `json:"key"`
+		} `json:"issue"`
+	}
+	_ = json.Unmarshal(body, &hook)
+	a.IssueKey = hook.Issue.Key
+
+	for name, values := range header {
+		if secretHeader(name) {
+			continue
+		}
+		a.Headers[name] = strings.Join(values, ", ")
+	}
+	return a
+}
+
+// secretHeader 判断请求头是否携带凭据
+func secretHeader(name string) bool {
+	switch http.CanonicalHeaderKey(name) {
+	case "Authorization", "Cookie":
+		return true
+	}
+	return appCfg != nil && appCfg.Webhook.TokenHeader != "" &&
+		http.CanonicalHeaderKey(name) == http.CanonicalHeaderKey(appCfg.Webhook.TokenHeader)
+}
+
+// archiveWebhook 压缩请求体后写入存档表
+func archiveWebhook(a *archivedWebhook) {
+	body, err := gzipBytes(a.Body)
+	if err != nil {
+		fmt.Printf("⚠️ 压缩 webhook 请求体失败: %v\n", err)
+		return
+	}
+	headers, _ := json.Marshal(a.Headers)
+
+	err = writeDB("jirahook_webhook_archive", func(db *sql.DB) error {
+		_, err := db.Exec(`INSERT INTO jirahook_webhook_archive
+            (received_at, event, action, issue_key, headers, body) VALUES (?, ?, ?, ?, ?, ?)`,
+			a.ReceivedAt, a.Event, a.Action, a.IssueKey, string(headers), body)
+		return err
+	})
+	if err != nil {
+		fmt.Printf("❌ 写入 MySQL jirahook_webhook_archive 失败: %v\n", err)
+	}
+}
+
+// gzipBytes 压缩数据
+func gzipBytes(data []byte) ([]byte, error) {
+	var buf bytes.Buffer
+	w := gzip.NewWriter(&buf)
+	if _, err := w.Write(data); err != nil {
+		return nil, err
+	}
+	if err := w.Close(); err != nil {
+		return nil, err
+	}
+	return buf.Bytes(), nil
+}
+
+// gunzipBytes 解压 gzipBytes 压缩的数据
+func gunzipBytes(data []byte) ([]byte, error) {
+	r, err := gzip.NewReader(bytes.NewReader(data))
+	if err != nil {
+		return nil, err
+	}
+	defer r.Close()
+	return io.ReadAll(r)
+}
+
+// runArchiveCleanup 定期删除超过保留时间的存档，直到 c 结束
+func runArchiveCleanup(c context.Context, retention time.Duration) {
+	ticker := time.NewTicker(archiveCleanupInterval)
+	defer ticker.Stop()
+	for {
+		if err := cleanupArchive(time.Now().Add(-retention)); err != nil {
+			log.Printf("⚠️ 清理 webhook 存档失败: %v", err)
+		}
+		select {
+		case <-c.Done():
+			return
+		case <-ticker.C:
+		}
+	}
+}
+
+// cleanupArchive 分批删除 before 之前收到的存档
+func cleanupArchive(before time.Time) error {
+	return withDB(func(db *sql.DB) error {
+		for {
+			res, err := db.Exec(`DELETE FROM jirahook_webhook_archive WHERE received_at < ? LIMIT ?`,
+				before, archiveCleanupBatch)
+			if err != nil {
+				return err
+			}
+			if n, _ := res.RowsAffected(); n < archiveCleanupBatch {
+				return nil
+			}
+		}
+	})
+}
+
+// ArchiveFilter 是重放存档时的筛选条件，零值表示不限
+type ArchiveFilter struct {
+	Since, Until time.Time
+	IssueKey     string
+	Event        string
+}
+
+// where 返回筛选条件对应的 WHERE 子句和参数
+func (f ArchiveFilter) where() (string, []interface{}) {
+	var conds []string
+	var args []interface{}
+	add := func(cond string, arg interface{}) {
+		conds = append(conds, cond)
+		args = append(args, arg)
+	}
+	if !f.Since.IsZero() {
+		add("received_at >= ?", f.Since)
+	}
+	if !f.Until.IsZero() {
+		add("received_at < ?", f.Until)
+	}
+	if f.IssueKey != "" {
+		add("issue_key = ?", f.IssueKey)
+	}
+	if f.Event != "" {
+		add("event = ?", f.Event)
+	}
+	if len(conds) == 0 {
+		return "", nil
+	}
+	return " WHERE " + strings.Join(conds, " AND "), args
+}
+
+// eachArchived 按收到的顺序逐条读取符合条件的存档
+// 先读出全部 ID 再逐条读取，避免处理存档时长时间占用查询连接
+func eachArchived(f ArchiveFilter, fn func(a *archivedWebhook) error) error {
+	var ids []int64
+	where, args := f.where()
+	err := withDB(func(db *sql.DB) error {
+		rows, err := db.Query(`SELECT id FROM jirahook_webhook_archive`+where+` ORDER BY received_at, id`, args...)
+		if err != nil {
+			return err
+		}
+		defer rows.Close()
+		for rows.Next() {
+			var id int64
+			if err := rows.Scan(&id); err != nil {
+				return err
+			}
+			ids = append(ids, id)
+		}
+		return rows.Err()
+	})
+	if err != nil {
+		return fmt.Errorf("查询 webhook 存档失败: %v", err)
+	}
+
+	for _, id := range ids {
+		a, err := loadArchived(id)
+		if err != nil {
+			return fmt.Errorf("读取 webhook 存档 %d 失败: %v", id, err)
+		}
+		if err := fn(a); err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+// loadArchived 读取一条存档并解压请求体
+func loadArchived(id int64) (*archivedWebhook, error) {
+	a := &archivedWebhook{ID: id}
+	var headers string
+	var body []byte
+	err := withDB(func(db *sql.DB) error {
+		return db.QueryRow(`SELECT received_at, event, action, issue_key, headers, body
+            FROM jirahook_webhook_archive WHERE id = ?`, id).
+			Scan(&a.ReceivedAt, &a.Event, &a.Action, &a.IssueKey, &headers, &body)
+	})
+	if err != nil {
+		return nil, err
+	}
+	if err := json.Unmarshal([]byte(headers), &a.Headers); err != nil {
+		return nil, fmt.Errorf("解析请求头失败: %v", err)
+	}
+	if a.Body, err = gunzipBytes(body); err != nil {
+		return nil, fmt.Errorf("解压请求体失败: %v", err)
+	}
+	return a, nil
+}
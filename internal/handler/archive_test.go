@@ -0,0 +1,56 @@
+package handler
+
+import (
+	"bytes"
+	"net/http"
+	"reflect"
+	"testing"
+	"time"
+	"whenchangesth/internal/conf"
+)
+
+func TestNewArchivedWebhook(t *testing.T) {
+	oldCfg := appCfg
+	appCfg = &conf.Config{Webhook: conf.WebhookConfig{TokenHeader: "X-Jirahook-Token"}}
+	defer func() { appCfg = oldCfg }()
+
+	header := http.Header{}
+	header.Set("Content-Type", "application/json")
+	header.Set("X-Atlassian-Webhook-Identifier", "7f3c")
+	header.Set("Authorization", "Bearer secret")
+	header.Set("X-Jirahook-Token", "secret")
+	body := []byte(`{"webhookEvent":"jira:issue_updated","issue_event_type_name":"issue_generic","issue":{"key":"ABC-1"}}`)
+	received := time.Date(2026, 10, 18, 9, 0, 0, 0, time.Local)
+
+	a := newArchivedWebhook(header, body, received)
+	if a.Event != "jira:issue_updated" || a.Action != "issue_generic" || a.IssueKey != "ABC-1" || !a.ReceivedAt.Equal(received) {
+		t.Errorf("archived = %+v", a)
+	}
+	want := map[string]string{"Content-Type": "application/json", "X-Atlassian-Webhook-Identifier": "7f3c"}
+	if !reflect.DeepEqual(a.Headers, want) {
+		t.Errorf("headers = %v, want %v", a.Headers, want)
+	}
+
+	packed, err := gzipBytes(body)
+	if err != nil {
+		t.Fatal(err)
+	}
+	unpacked, err := gunzipBytes(packed)
+	if err != nil || !bytes.Equal(unpacked, body) {
+		t.Errorf("gunzip = %q, %v", unpacked, err)
+	}
+}
+
+func TestArchiveFilterWhere(t *testing.T) {
+	if where, args := (ArchiveFilter{}).where(); where != "" || args != nil {
+		t.Errorf("empty filter = %q %v", where, args)
+	}
+	since := time.Date(2026, 10, 1, 0, 0, 0, 0, time.Local)
+	where, args := ArchiveFilter{Since: since, IssueKey: "ABC-1", Event: "jira:issue_updated"}.where()
+	if where != " WHERE received_at >= ? AND issue_key = ? AND event = ?" {
+		t.Errorf("where = %q", where)
+	}
+	if !reflect.DeepEqual(args, []interface{}{since, "ABC-1", "jira:issue_updated"}) {
+		t.Errorf("args = %v", args)
+	}
+}
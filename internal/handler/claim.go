@@ -0,0 +1,194 @@
+package handler
+
+import (
+	"fmt"
+	"strconv"
+	"strings"
+	"time"
+
+	"github.com/go-redis/redis/v8"
+)
+
+// 多实例部署时，同一去抖桶由持有锁的实例认领并发送
+// 认领时将桶原子地改名为带有防护令牌的 Key，之后写入的事件进入新的桶，不会被漏发或重复发送
+const (
+	fenceKey         = "issue_event_fence"
+	lockKeyPrefix    = "issue_event_lock:"
+	claimKeyPrefix   = "issue_event_claim:"
+	claimPhonePrefix = "issue_event_claim_phone:"
+
+	lockTTL    = time.Minute     // 锁的有效期，应长于一次发送的耗时
+	retryDelay = 5 * time.Second // 桶正被其他实例发送时，推迟再次发送的时间
+)
+
+// claimScript 在桶存在时将事件列表和手机号集合改名为认领 Key
+var claimScript = redis.NewScript(`
+if redis.call('EXISTS', KEYS[1]) == 0 then
+    return 0
+end
+redis.call('RENAME', KEYS[1], KEYS[3])
+if redis.call('EXISTS', KEYS[2]) == 1 then
+    redis.call('RENAME', KEYS[2], KEYS[4])
+end
+return 1`)
+
+// releaseScript 删除认领的数据，锁仍属于本次认领时释放锁
+var releaseScript = redis.NewScript(`
+redis.call('DEL', KEYS[2], KEYS[3])
+if redis.call('GET', KEYS[1]) == ARGV[1] then
+    redis.call('DEL', KEYS[1])
+end
+return 1`)
+
+// renewScript 在锁仍属于本次认领时延长锁的有效期
+var renewScript = redis.NewScript(`
+if redis.call('GET', KEYS[1]) == ARGV[1] then
+    return redis.call('PEXPIRE', KEYS[1], ARGV[2])
+end
+return 0`)
+
+// bucketClaim 是一次认领，token 为认领时取得的防护令牌
+type bucketClaim struct {
+	robot, eventType, operator string
+	token                      int64
+	listKey, phoneKey          string
+}
+
+// lockKey 返回去抖桶的锁 Key
+func lockKey(robot, eventType, operator string) string {
+	return fmt.Sprintf("%s%s:%s:%s", lockKeyPrefix, robot, eventType, operator)
+}
+
+// claimKeys 返回某个防护令牌下认领的事件列表和手机号集合 Key
+func claimKeys(token int64, robot, eventType, operator string) (listKey, phoneKey string) {
+	suffix := fmt.Sprintf("%d:%s:%s:%s", token, robot, eventType, operator)
+	return claimKeyPrefix + suffix, claimPhonePrefix + suffix
+}
+
+// parseClaimKey 从认领 Key 中解析防护令牌、机器人、事件类型和操作人
+func parseClaimKey(listKey string) (c bucketClaim, ok bool) {
+	rest, found := strings.CutPrefix(listKey, claimKeyPrefix)
+	if !found {
+		return c, false
+	}
+	parts := strings.SplitN(rest, ":", 4)
+	if len(parts) != 4 {
+		return c, false
+	}
+	token, err := strconv.ParseInt(parts[0], 10, 64)
+	if err != nil {
+		return c, false
+	}
+	c = bucketClaim{robot: parts[1], eventType: parts[2], operator: parts[3], token: token}
+	c.listKey, c.phoneKey = claimKeys(token, c.robot, c.eventType, c.operator)
+	return c, true
+}
+
+// acquireLock 取得新的防护令牌并尝试加锁，锁已被其他实例持有时返回 false
+func acquireLock(robot, eventType, operator string) (int64, bool, error) {
+	token, err := RedisClient.Incr(ctx, fenceKey).Result()
+	if err != nil {
+		return 0, false, fmt.Errorf("获取防护令牌失败: %v", err)
+	}
+	ok, err := RedisClient.SetNX(ctx, lockKey(robot, eventType, operator), token, lockTTL).Result()
+	if err != nil {
+		return 0, false, fmt.Errorf("加锁失败: %v", err)
+	}
+	return token, ok, nil
+}
+
+// held 判断锁是否仍属于本次认领
+func (c bucketClaim) held() bool {
+	v, err := RedisClient.Get(ctx, lockKey(c.robot, c.eventType, c.operator)).Result()
+	return err == nil && v == strconv.FormatInt(c.token, 10)
+}
+
+// keepAlive 在等待发送期间定期延长锁，避免限流排队较久时被其他实例当作遗留的认领重复发送
+// 返回的函数停止续期
+func (c bucketClaim) keepAlive() (stop func()) {
+	done := make(chan struct{})
+	go func() {
+		ticker := time.NewTicker(lockTTL / 3)
+		defer ticker.Stop()
+		for {
+			select {
+			case <-done:
+				return
+			case <-ticker.C:
+				key := lockKey(c.robot, c.eventType, c.operator)
+				if err := renewScript.Run(ctx, RedisClient, []string{key}, c.token, lockTTL.Milliseconds()).Err(); err != nil {
+					fmt.Printf("⚠️ 延长去抖桶 %s 的锁失败: %v\n", c.listKey, err)
+				}
+			}
+		}
+	}()
+	return func() { close(done) }
+}
+
+// release 删除认领的数据并释放锁
+func (c bucketClaim) release() {
+	keys := []string{lockKey(c.robot, c.eventType, c.operator), c.listKey, c.phoneKey}
+	if err := releaseScript.Run(ctx, RedisClient, keys, c.token).Err(); err != nil {
+		fmt.Printf("⚠️ 释放去抖桶 %s 失败: %v\n", c.listKey, err)
+	}
+}
+
+// flushBucket 加锁并认领去抖桶后发送
+// 其他实例正在发送同一个桶时推迟发送，桶已被认领或为空时直接返回
+func flushBucket(robot, eventType, operator string) {
+	summaryKey, phoneKey := bucketKeys(robot, eventType, operator)
+	token, ok, err := acquireLock(robot, eventType, operator)
+	if err != nil {
+		fmt.Printf("⚠️ %v\n", err)
+	}
+	if !ok {
+		// 已有新事件写入计划时保留原计划
+		if _, err := scheduleFlushIfAbsent(summaryKey, time.Now().Add(retryDelay)); err != nil {
+			fmt.Printf("⚠️ 写入发送计划失败: %v\n", err)
+		}
+		return
+	}
+
+	c := bucketClaim{robot: robot, eventType: eventType, operator: operator, token: token}
+	c.listKey, c.phoneKey = claimKeys(token, robot, eventType, operator)
+	claimed, err := claimScript.Run(ctx, RedisClient, []string{summaryKey, phoneKey, c.listKey, c.phoneKey}).Int()
+	if err != nil || claimed == 0 {
+		if err != nil {
+			fmt.Printf("⚠️ 认领去抖桶 %s 失败: %v\n", summaryKey, err)
+		}
+		c.release()
+		return
+	}
+	sendEventSummariesAndNotifications(c)
+}
+
+// recoverOrphanClaims 发送已认领但未完成的去抖桶，通常是发送中途退出的实例留下的
+// 锁仍被持有的认领跳过，由持有者继续处理
+func recoverOrphanClaims() error {
+	var cursor uint64
+	for {
+		keys, next, err := RedisClient.Scan(ctx, cursor, claimKeyPrefix+"*", 100).Result()
+		if err != nil {
+			return fmt.Errorf("扫描认领的去抖桶失败: %v", err)
+		}
+		for _, key := range keys {
+			orphan, ok := parseClaimKey(key)
+			if !ok {
+				continue
+			}
+			token, ok, err := acquireLock(orphan.robot, orphan.eventType, orphan.operator)
+			if err != nil {
+				return err
+			}
+			if !ok {
+				continue
+			}
+			// 以新的令牌接手原有的认领数据
+			orphan.token = token
+			goBackground(func() { sendEventSummariesAndNotifications(orphan) })
+		}
+		if cursor = next; cursor == 0 {
+			return nil
+		}
+	}
+}
@@ -0,0 +1,165 @@
+package handler
+
+import (
+	"strings"
+	"sync"
+	"testing"
+)
+
+func TestParseClaimKey(t *testing.T) {
+	listKey, phoneKey := claimKeys(42, "qa", EventCreate, "张三:外包")
+	c, ok := parseClaimKey(listKey)
+	if !ok || c.token != 42 || c.robot != "qa" || c.eventType != EventCreate || c.operator != "张三:外包" {
+		t.Fatalf("parseClaimKey(%q) = %+v, %v", listKey, c, ok)
+	}
+	if c.listKey != listKey || c.phoneKey != phoneKey {
+		t.Fatalf("keys = %q, %q", c.listKey, c.phoneKey)
+	}
+	if _, ok := parseClaimKey(phoneKey); ok {
+		t.Error("phone key should not parse as a claim")
+	}
+}
+
+// claimBucket 加锁并认领去抖桶，模拟认领后尚未发送的实例
+func claimBucket(t *testing.T, robot, eventType, operator string) bucketClaim {
+	t.Helper()
+	token, ok, err := acquireLock(robot, eventType, operator)
+	if err != nil || !ok {
+		t.Fatalf("acquireLock = %v, %v", ok, err)
+	}
+	c := bucketClaim{robot: robot, eventType: eventType, operator: operator, token: token}
+	c.listKey, c.phoneKey = claimKeys(token, robot, eventType, operator)
+	summaryKey, phoneKey := bucketKeys(robot, eventType, operator)
+	if err := claimScript.Run(ctx, RedisClient, []string{summaryKey, phoneKey, c.listKey, c.phoneKey}).Err(); err != nil {
+		t.Fatal(err)
+	}
+	return c
+}
+
+func TestLockBelongsToClaim(t *testing.T) {
+	mr := useMiniRedis(t)
+	first := claimBucket(t, "team", EventCreate, "张三")
+	if !first.held() {
+		t.Fatal("lock not held after claim")
+	}
+	if _, ok, _ := acquireLock("team", EventCreate, "张三"); ok {
+		t.Fatal("second claimer acquired a held lock")
+	}
+
+	mr.FastForward(lockTTL)
+	if first.held() {
+		t.Fatal("lock still held after expiry")
+	}
+	second := claimBucket(t, "team", EventCreate, "张三")
+
+	// 过期的认领不能延长或释放接手者的锁
+	key := lockKey("team", EventCreate, "张三")
+	if n, _ := renewScript.Run(ctx, RedisClient, []string{key}, first.token, lockTTL.Milliseconds()).Int(); n != 0 {
+		t.Error("stale claim renewed the new lock")
+	}
+	first.release()
+	if !second.held() {
+		t.Error("stale release deleted the new lock")
+	}
+	second.release()
+	if n := RedisClient.Exists(ctx, key).Val(); n != 0 {
+		t.Error("lock not released by its owner")
+	}
+}
+
+func TestFlushBucketTwoClaimers(t *testing.T) {
+	useMiniRedis(t)
+	var log sentLog
+	stubDelivery(t, "team", log.send)
+	PushEventArgumentsAndPhones(&eventArgs{eventType: EventCreate, operator: "张三", summaryKeyID: "ABC-1", summary: "任务A", robots: []string{"team"}})
+
+	var wg sync.WaitGroup
+	for i := 0; i < 2; i++ {
+		wg.Add(1)
+		go func() {
+			defer wg.Done()
+			flushBucket("team", EventCreate, "张三")
+		}()
+	}
+	wg.Wait()
+	background.Wait()
+
+	if sent := log.sent(); len(sent) != 1 {
+		t.Fatalf("sent %d messages, want 1: %q", len(sent), sent)
+	}
+	if keys := RedisClient.Keys(ctx, claimKeyPrefix+"*").Val(); len(keys) != 0 {
+		t.Errorf("claims left: %q", keys)
+	}
+}
+
+func TestRecoverOrphanClaimsAfterLockExpiry(t *testing.T) {
+	mr := useMiniRedis(t)
+	var log sentLog
+	stubDelivery(t, "team", log.send)
+	PushEventArgumentsAndPhones(&eventArgs{eventType: EventCreate, operator: "张三", summaryKeyID: "ABC-1", summary: "任务A", robots: []string{"team"}})
+	c := claimBucket(t, "team", EventCreate, "张三")
+
+	// 锁仍被持有时由持有者继续处理
+	if err := recoverOrphanClaims(); err != nil {
+		t.Fatal(err)
+	}
+	background.Wait()
+	if sent := log.sent(); len(sent) != 0 {
+		t.Fatalf("claim taken over while locked: %q", sent)
+	}
+
+	mr.FastForward(lockTTL)
+	if err := recoverOrphanClaims(); err != nil {
+		t.Fatal(err)
+	}
+	background.Wait()
+	if sent := log.sent(); len(sent) != 1 || !strings.Contains(sent[0], "任务A") {
+		t.Fatalf("sent = %q, want the orphaned claim", sent)
+	}
+	if RedisClient.Exists(ctx, c.listKey).Val() != 0 {
+		t.Error("claim not released after send")
+	}
+}
+
+func TestSendKeepsClaimOnReadError(t *testing.T) {
+	mr := useMiniRedis(t)
+	var log sentLog
+	stubDelivery(t, "team", log.send)
+	PushEventArgumentsAndPhones(&eventArgs{
+		eventType: EventCreate, operator: "张三", summaryKeyID: "ABC-1", summary: "任务A", robots: []string{"team"},
+		mentions: []mention{{Mobile: "138"}},
+	})
+	c := claimBucket(t, "team", EventCreate, "张三")
+
+	// Redis 暂时不可用时保留认领
+	mr.SetError("LOADING Redis is loading the dataset in memory")
+	sendEventSummariesAndNotifications(c)
+	mr.SetError("")
+	if RedisClient.Exists(ctx, c.listKey).Val() != 1 || !c.held() {
+		t.Fatal("claim dropped after a read error")
+	}
+
+	// 读不到需要 @ 的人时不发送
+	phones := RedisClient.SMembers(ctx, c.phoneKey).Val()
+	RedisClient.Del(ctx, c.phoneKey)
+	RedisClient.Set(ctx, c.phoneKey, "wrong type", 0)
+	sendEventSummariesAndNotifications(c)
+	background.Wait()
+	if sent := log.sent(); len(sent) != 0 {
+		t.Fatalf("sent without mentions: %q", sent)
+	}
+	if RedisClient.Exists(ctx, c.listKey).Val() != 1 {
+		t.Fatal("claim dropped after a mention read error")
+	}
+
+	RedisClient.Del(ctx, c.phoneKey)
+	RedisClient.SAdd(ctx, c.phoneKey, phones)
+	sendEventSummariesAndNotifications(c)
+	background.Wait()
+	if sent := log.sent(); len(sent) != 1 || !strings.Contains(sent[0], "@138") {
+		t.Fatalf("sent = %q, want one message mentioning 138", sent)
+	}
+	if RedisClient.Exists(ctx, c.listKey, c.phoneKey).Val() != 0 {
+		t.Error("claim not released after send")
+	}
+}
@@ -0,0 +1,118 @@
+package handler
+
+import (
+	"fmt"
+	"log"
+	"regexp"
+	"strings"
+	"time"
+	"whenchangesth/internal/conf"
+	"whenchangesth/internal/objects"
+	"whenchangesth/internal/rules"
+)
+
+// 评论事件的消息类型
+const (
+	EventCommentCreate = "comment_created"
+	EventCommentUpdate = "comment_updated"
+	EventCommentDelete = "comment_deleted"
+)
+
+// Jira 对同一条评论会同时发送 comment_* 和带 issue_commented 等动作的 jira:issue_updated，
+// 两者的 webhook 标识不同，按评论 ID 去重，只处理先到的一个
+const (
+	commentSeenPrefix = "comment_seen:"
+	commentSeenTTL    = 10 * time.Minute
+)
+
+// commentEventTypes 是评论事件名到消息类型的映射
+var commentEventTypes = map[string]string{
+	rules.EventCommentCreated: EventCommentCreate,
+	rules.EventCommentUpdated: EventCommentUpdate,
+	rules.EventCommentDeleted: EventCommentDelete,
+}
+
+// jiraMentionPattern 匹配 Jira 评论中的 [~username] 和 [~accountid:xxx]
+var jiraMentionPattern = regexp.MustCompile(`\[~(accountid:)?([^\]]+)\]`)
+
+// commentAuthor 返回评论的最后编辑人，没有时返回作者
+func commentAuthor(c *objects.Comment) *objects.User {
+	if c == nil {
+		return nil
+	}
+	if c.UpdateAuthor != nil {
+		return c.UpdateAuthor
+	}
+	return c.Author
+}
+
+// mentionedRef 将评论中的一处 @ 转换为通讯录查找条件
+func mentionedRef(match []string) conf.UserRef {
+	if match[1] != "" {
+		return conf.UserRef{AccountID: match[2]}
+	}
+	return conf.UserRef{Key: match[2], Name: match[2]}
+}
+
+// commentMentions 解析评论中 @ 到的人
+func commentMentions(body string) []mention {
+	var mentions []mention
+	for _, match := range jiraMentionPattern.FindAllStringSubmatch(body, -1) {
+		if p, ok := phoneBook.Resolve(mentionedRef(match)); ok {
+			mentions = appendMentions(mentions, mention{Mobile: p.Phone, UserID: p.DingUserID})
+		}
+	}
+	return mentions
+}
+
+// commentText 将评论正文转换为适合引用的单行文本，Jira 的 @ 标记替换为人名
+func commentText(body string) string {
+	body = jiraMentionPattern.ReplaceAllStringFunc(body, func(s string) string {
+		match := jiraMentionPattern.FindStringSubmatch(s)
+		if p, ok := phoneBook.Resolve(mentionedRef(match)); ok && p.Name != "" {
+			return "@" + p.Name
+		}
+		return "@" + match[2]
+	})
+	return strings.Join(strings.Fields(body), " ")
+}
+
+// handleComment 处理独立的评论创建、编辑和删除事件
+func handleComment(payload interface{}) error {
+	e, ok := issueEventOf(payload)
+	if !ok || e.comment == nil {
+		return fmt.Errorf("invalid payload type for comment: %T", payload)
+	}
+	if e.issue == nil {
+		log.Printf("评论 %s 缺少所属问题，跳过", e.comment.ID)
+		return nil
+	}
+	if !markCommentSeen(e.event, e.comment) {
+		log.Printf("评论 %s 的 %s 事件已处理，跳过", e.comment.ID, e.event)
+		return nil
+	}
+	return processIssueEvent(e)
+}
+
+// commentSeenKey 返回评论事件的去重 Key，编辑按修改时间区分，多次编辑各自通知
+func commentSeenKey(event string, c *objects.Comment) string {
+	key := commentSeenPrefix + event + ":" + c.ID
+	if event == rules.EventCommentUpdated {
+		key += ":" + c.Updated
+	}
+	return key
+}
+
+// markCommentSeen 记录评论事件，已经记录过时返回 false
+// 演练、评论没有 ID 或 Redis 不可用时返回 true，宁可重复通知也不丢弃事件
+func markCommentSeen(event string, c *objects.Comment) bool {
+	if dryRun != nil || c.ID == "" {
+		return true
+	}
+	first, err := RedisClient.SetNX(ctx, commentSeenKey(event, c), 1, commentSeenTTL).Result()
+	if err != nil {
+		fmt.Printf("⚠️ 记录评论 %s 失败，跳过去重: %v\n", c.ID, err)
+		return true
+	}
+	return first
+}
@@ -0,0 +1,63 @@
+package handler
+
+import (
+	"os"
+	"path/filepath"
+	"testing"
+	"whenchangesth/internal/conf"
+	"whenchangesth/internal/objects"
+	"whenchangesth/internal/rules"
+	"whenchangesth/pkg"
+)
+
+func TestCommentMentions(t *testing.T) {
+	filePath := filepath.Join(t.TempDir(), "phonenumb.yaml")
+	data := `people:
+  - name: 张三
+    phone: "13800000000"
+    jira_account_id: "5b10a2844c20165700ede21g"
+  - name: 李四
+    ding_user_id: lisi01
+    jira_name: lisi
+`
+	if err := os.WriteFile(filePath, []byte(data), 0o600); err != nil {
+		t.Fatal(err)
+	}
+	pb, err := conf.NewPhoneBook(filePath)
+	if err != nil {
+		t.Fatal(err)
+	}
+	phoneBook = pb
+	t.Cleanup(func() { phoneBook = nil })
+
+	body := "请 [~accountid:5b10a2844c20165700ede21g] 和 [~lisi]\n看一下，[~unknown] 不用管 [~lisi]"
+
+	mentions := commentMentions(body)
+	if len(mentions) != 2 || mentions[0].Mobile != "13800000000" || mentions[1].UserID != "lisi01" {
+		t.Fatalf("mentions = %+v", mentions)
+	}
+	if got, want := commentText(body), "请 @张三 和 @李四 看一下，@unknown 不用管 @李四"; got != want {
+		t.Fatalf("commentText = %q, want %q", got, want)
+	}
+}
+
+func TestCommentSeenKey(t *testing.T) {
+	c := &objects.Comment{ID: "10001", Updated: "2026-10-18T09:00:00.000+0800"}
+	issue := &objects.Issue{Key: "ABC-1"}
+
+	// 独立的评论事件和问题更新中的评论动作得到同一个 Key
+	standalone, _ := issueEventOf(pkg.CommentCreatedPayload{Comment: c, Issue: issue})
+	embedded, _ := issueEventOf(pkg.IssueCommentCreatedPayload{Comment: c, Issue: issue})
+	if a, b := commentSeenKey(standalone.event, c), commentSeenKey(embedded.event, c); a != b {
+		t.Errorf("keys differ: %q vs %q", a, b)
+	}
+
+	edited := *c
+	edited.Updated = "2026-10-18T09:05:00.000+0800"
+	if commentSeenKey(rules.EventCommentUpdated, c) == commentSeenKey(rules.EventCommentUpdated, &edited) {
+		t.Error("later edit shares the key of the earlier one")
+	}
+	if commentSeenKey(rules.EventCommentCreated, c) == commentSeenKey(rules.EventCommentDeleted, c) {
+		t.Error("created and deleted share a key")
+	}
+}
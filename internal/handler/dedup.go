@@ -0,0 +1,82 @@
+package handler
+
+import (
+	"encoding/json"
+	"fmt"
+	"strings"
+	"time"
+	"whenchangesth/internal/metrics"
+)
+
+// Jira 重试投递时携带相同的 webhook 标识，已处理过的标识在 Redis 中保留一段时间，重复投递直接跳过
+const (
+	webhookIdentifierHeader = "X-Atlassian-Webhook-Identifier"
+	seenKeyPrefix           = "webhook_seen:"
+)
+
+// idempotencyKey 返回 webhook 的幂等键，优先使用请求头中的标识
+// 没有标识时由事件、问题、变更记录 ID 和时间戳组成，payload 中没有时间戳时返回空字符串，不做去重
+func idempotencyKey(identifier string, body []byte) string {
+	if identifier = strings.TrimSpace(identifier); identifier != "" {
+		return "id:" + identifier
+	}
+	var hook struct {
+		Event     string          `json:"webhookEvent"`
+		Timestamp json.RawMessage `json:"timestamp"`
+		Issue     struct {
+			ID  json.RawMessage `json:"id"`
+			Key string          `json:"key"`
+		} `json:"issue"`
+		Changelog struct {
+			ID json.RawMessage `json:"id"`
+		} `json:"changelog"`
+	}
+	if err := json.Unmarshal(body, &hook); err != nil {
+		return ""
+	}
+	timestamp := rawString(hook.Timestamp)
+	if timestamp == "" {
+		return ""
+	}
+	issue := hook.Issue.Key
+	if issue == "" {
+		issue = rawString(hook.Issue.ID)
+	}
+	return fmt.Sprintf("payload:%s:%s:%s:%s", hook.Event, issue, rawString(hook.Changelog.ID), timestamp)
+}
+
+// rawString 返回 JSON 数字或字符串的文本，Jira Server 和 Cloud 中同一字段的类型可能不同
+func rawString(raw json.RawMessage) string {
+	s := strings.Trim(string(raw), `"`)
+	if s == "null" {
+		return ""
+	}
+	return s
+}
+
+// markWebhookSeen 记录幂等键，已经记录过时返回 false
+// Redis 不可用时返回 true，宁可重复通知也不丢弃事件
+func markWebhookSeen(key string, ttl time.Duration) bool {
+	if key == "" || ttl <= 0 {
+		return true
+	}
+	first, err := RedisClient.SetNX(ctx, seenKeyPrefix+key, 1, ttl).Result()
+	if err != nil {
+		fmt.Printf("⚠️ 记录 webhook 幂等键失败，跳过去重: %v\n", err)
+		return true
+	}
+	if !first {
+		metrics.WebhookDuplicates.Inc()
+	}
+	return first
+}
+
+// forgetWebhook 删除幂等键，webhook 未能处理时让 Jira 的重试投递可以再次处理
+func forgetWebhook(key string) {
+	if key == "" {
+		return
+	}
+	if err := RedisClient.Del(ctx, seenKeyPrefix+key).Err(); err != nil {
+		fmt.Printf("⚠️ 删除 webhook 幂等键失败: %v\n", err)
+	}
+}
@@ -0,0 +1,28 @@
+package handler
+
+import "testing"
+
+func TestIdempotencyKey(t *testing.T) {
+	cases := []struct {
+		name       string
+		identifier string
+		body       string
+		want       string
+	}{
+		{"header", " 7f3c ", `{"webhookEvent":"jira:issue_updated","timestamp":1}`, "id:7f3c"},
+		{"payload", "", `{"webhookEvent":"jira:issue_updated","timestamp":1760000000000,"issue":{"id":"10001","key":"ABC-1"},"changelog":{"id":"20002"}}`,
+			"payload:jira:issue_updated:ABC-1:20002:1760000000000"},
+		{"numeric ids", "", `{"webhookEvent":"jira:issue_created","timestamp":5,"issue":{"id":10001},"changelog":{"id":20002}}`,
+			"payload:jira:issue_created:10001:20002:5"},
+		{"no changelog", "", `{"webhookEvent":"sprint_started","timestamp":5}`, "payload:sprint_started:::5"},
+		{"no timestamp", "", `{"webhookEvent":"jira:issue_updated","issue":{"key":"ABC-1"}}`, ""},
+		{"invalid body", "", `not json`, ""},
+	}
+	for _, tc := range cases {
+		t.Run(tc.name, func(t *testing.T) {
+			if got := idempotencyKey(tc.identifier, []byte(tc.body)); got != tc.want {
+				t.Errorf("idempotencyKey() = %q, want %q", got, tc.want)
+			}
+		})
+	}
+}
@@ -4,20 +4,19 @@ import (
 	"database/sql"
 	"encoding/json"
 	"fmt"
-	_ "github.com/go-sql-driver/mysql"
 	"sync"
+	"sync/atomic"
 	"time"
 	"whenchangesth/internal/conf"
+	"whenchangesth/internal/ding"
+	"whenchangesth/internal/metrics"
 )
 
-// 用户ID -> 定时器（用于去重和刷新）
-var debounceTimers = make(map[string]*time.Timer)
-var timerLock sync.Mutex // 保护 map 并发访问
-
-// Redis 存活时间和定时器延迟时间
+// Redis 存活时间和去抖延迟时间
+// 发送时间记录在 Redis 中，服务重启后仍会发送，TTL 只用于清理异常残留的数据
 const (
-	redisTTL   = 3 * time.Minute // Redis 数据存活时间稍长于定时器
-	timerDelay = 2 * time.Minute // 定时器延迟执行时间
+	redisTTL   = 24 * time.Hour  // Redis 数据存活时间
+	timerDelay = 2 * time.Minute // 去抖延迟时间
 )
 const (
 	EventCreate         = "created"
@@ -25,6 +24,7 @@ const (
 	EventUpdateReport   = "updated_report"
 	EventUpdateAssigner = "updated_assigner"
 	EventUpdateStatus   = "updated_status"
+	EventUpdateField    = "updated_field"
 )
 
 type eventArgs struct {
@@ -39,118 +39,147 @@ type eventArgs struct {
 	status,
 	statusFrom,
 	statusTo,
+	field,
+	fieldFrom,
+	fieldTo,
+	link,
+	comment,
 	summary string
+	mentions  []mention
+	robots    []string
+	immediate bool
+}
+
+// bucketKeys 返回某个机器人下某类事件、某个操作人的 Redis Key
+func bucketKeys(robot, eventType, operator string) (summaryKey, phoneKey string) {
+	summaryKey = fmt.Sprintf("issue_event_summary:%s:%s:%s", robot, eventType, operator)
+	phoneKey = fmt.Sprintf("issue_event_phone:%s:%s:%s", robot, eventType, operator)
+	return summaryKey, phoneKey
 }
 
+// saveEventData 将事件写入 jirahook_eventdata，不论是否发送通知都会记录
+func saveEventData(args *eventArgs) {
+	// 在 webhook worker 中同步写入，并发数受 worker 数量限制
+	err := writeDB("jirahook_eventdata", func(db *sql.DB) error {
+		query := `
+            INSERT INTO jirahook_eventdata 
+            (event_type, summary_key_id, operator, assignee_phone, reporter_phone, 
+            rpt_from, rpt_to, assigner_from_to, status, status_from, status_to, summary,
+            field, field_from, field_to) 
+            VALUES (?, ?, ?, ?, ?, ?, ?, ?, ?, ?, ?, ?, ?, ?, ?)`
+
+		_, err := db.Exec(query,
+			args.eventType,
+			args.summaryKeyID,
+			args.operator,
+			args.assigneePhone,
+			args.reporterPhone,
+			args.rptFrom,
+			args.rptTo,
+			args.assignerFromTo,
+			args.status,
+			args.statusFrom,
+			args.statusTo,
+			args.summary,
+			args.field,
+			args.fieldFrom,
+			args.fieldTo,
+		)
+		return err
+	})
+
+	if err != nil {
+		fmt.Printf("❌ 写入 MySQL jirahook_eventdata 失败: %v\n", err)
+	}
+}
+
+// PushEventArgumentsAndPhones 将事件和需要 @ 的人写入各机器人的去抖桶
 func PushEventArgumentsAndPhones(args *eventArgs) {
-	// 构造 Redis Key
-	summaryKey := fmt.Sprintf("issue_event_summary:%s:%s", args.eventType, args.operator)
-	phoneKey := fmt.Sprintf("issue_event_phone:%s:%s", args.eventType, args.operator)
+	robots := args.robots
+	if len(robots) == 0 {
+		robots = []string{conf.DefaultRobot}
+	}
 
 	// 将 eventArgs 转换为 JSON 存入 Redis List
-	eventData, _ := json.Marshal(map[string]string{
+	event := map[string]string{
 		"summaryKeyID":   args.summaryKeyID,
 		"summary":        args.summary,
+		"link":           args.link,
+		"comment":        args.comment,
 		"rptFrom":        args.rptFrom,
 		"rptTo":          args.rptTo,
 		"assignerFromTo": args.assignerFromTo,
 		"status":         args.status,
 		"statusFrom":     args.statusFrom,
 		"statusTo":       args.statusTo,
-	})
-	err := RedisClient.RPush(ctx, summaryKey, eventData).Err()
-	if err != nil {
-		fmt.Printf("⚠️ 写入 Redis issue_%s 失败: %v\n", args.eventType, err)
-	} else {
-		RedisClient.Expire(ctx, summaryKey, redisTTL)
+		"field":          args.field,
+		"fieldFrom":      args.fieldFrom,
+		"fieldTo":        args.fieldTo,
 	}
-	go func() {
-		mysqlConf, err := conf.ParseMySQLConfig()
-		if err != nil {
-			fmt.Printf("❌ 加载 MySQL 配置失败: %v\n", err)
-			return
+	eventData, _ := json.Marshal(event)
+
+	// 收集需要 @ 的人
+	var tokens []interface{}
+	for _, m := range args.mentions {
+		if t := m.token(); t != "" {
+			tokens = append(tokens, t)
 		}
+	}
 
-		err = WithMySQL(mysqlConf, func(db *sql.DB) error {
-			query := `
-            INSERT INTO jirahook_eventdata 
-            (event_type, summary_key_id, operator, assignee_phone, reporter_phone, 
-            rpt_from, rpt_to, assigner_from_to, status, status_from, status_to, summary) 
-            VALUES (?, ?, ?, ?, ?, ?, ?, ?, ?, ?, ?, ?)`
+	// 演练时只在内存中汇总，结束时输出渲染结果
+	if dryRun != nil {
+		dryRun.push(robots, args.eventType, args.operator, event, tokens)
+		return
+	}
 
-			_, err := db.Exec(query,
-				args.eventType,
-				args.summaryKeyID,
-				args.operator,
-				args.assigneePhone,
-				args.reporterPhone,
-				args.rptFrom,
-				args.rptTo,
-				args.assignerFromTo,
-				args.status,
-				args.statusFrom,
-				args.statusTo,
-				args.summary,
-			)
-			return err
-		})
+	// 每个机器人各自维护一个去抖桶
+	for _, robot := range robots {
+		summaryKey, phoneKey := bucketKeys(robot, args.eventType, args.operator)
 
+		err := RedisClient.RPush(ctx, summaryKey, eventData).Err()
 		if err != nil {
-			fmt.Printf("❌ 写入 MySQL jirahook_eventdata 失败: %v\n", err)
+			fmt.Printf("⚠️ 写入 Redis issue_%s 失败: %v\n", args.eventType, err)
+		} else {
+			RedisClient.Expire(ctx, summaryKey, redisTTL)
 		}
-	}()
 
-	// 收集手机号并存入 Set
-	var phones []string
-	if args.assigneePhone != "" {
-		phones = append(phones, args.assigneePhone)
-	}
-	if args.reporterPhone != "" {
-		phones = append(phones, args.reporterPhone)
-	}
-	if len(phones) > 0 {
-		interfacePhones := make([]interface{}, len(phones))
-		for i, p := range phones {
-			interfacePhones[i] = p
+		if len(tokens) > 0 {
+			if err := RedisClient.SAdd(ctx, phoneKey, tokens...).Err(); err != nil {
+				fmt.Printf("⚠️ 写入 Redis issue_%s_phone 失败: %v\n", args.eventType, err)
+			} else {
+				RedisClient.Expire(ctx, phoneKey, redisTTL)
+			}
 		}
-		if err := RedisClient.SAdd(ctx, phoneKey, interfacePhones...).Err(); err != nil {
-			fmt.Printf("⚠️ 写入 Redis issue_%s_phone 失败: %v\n", args.eventType, err)
+
+		if args.immediate {
+			err = scheduleFlushBy(summaryKey, time.Now())
 		} else {
-			RedisClient.Expire(ctx, phoneKey, redisTTL)
+			err = scheduleFlush(summaryKey, time.Now().Add(timerDelay))
+		}
+		if err != nil {
+			fmt.Printf("⚠️ 写入发送计划失败: %v\n", err)
 		}
 	}
-
-	setIssueDebounceTimer(args.eventType, args.operator)
-}
-
-func setIssueDebounceTimer(eventType, operator string) {
-	timerLock.Lock()
-	defer timerLock.Unlock()
-
-	key := fmt.Sprintf("timer_key:%s:%s", eventType, operator)
-
-	if oldTimer, exists := debounceTimers[key]; exists {
-		oldTimer.Stop()
-	}
-
-	newTimer := time.AfterFunc(timerDelay, func() {
-		sendEventSummariesAndNotifications(eventType, operator)
-	})
-
-	debounceTimers[key] = newTimer
 }
 
-func sendEventSummariesAndNotifications(eventType, operator string) {
-	summaryKey := fmt.Sprintf("issue_event_summary:%s:%s", eventType, operator)
-	phoneKey := fmt.Sprintf("issue_event_phone:%s:%s", eventType, operator)
-
+// sendEventSummariesAndNotifications 发送已认领的去抖桶并释放认领
+// 发送前确认锁仍由本次认领持有，锁已失效时放弃发送，留给接手的实例处理
+func sendEventSummariesAndNotifications(c bucketClaim) {
 	// 获取所有事件数据
-	rawEvents, err := RedisClient.LRange(ctx, summaryKey, 0, -1).Result()
-	if err != nil || len(rawEvents) == 0 {
-		fmt.Printf("❌ 没有找到事件数据: %v\n", err)
+	rawEvents, err := RedisClient.LRange(ctx, c.listKey, 0, -1).Result()
+	if err != nil {
+		// 保留认领，锁过期后由 recoverOrphanClaims 重新发送
+		fmt.Printf("⚠️ 读取去抖桶 %s 失败，稍后重试: %v\n", c.listKey, err)
+		return
+	}
+	if len(rawEvents) == 0 {
+		fmt.Printf("❌ 没有找到事件数据: %s\n", c.listKey)
+		c.release()
 		return
 	}
 
+	metrics.DebounceBucketSize.Observe(float64(len(rawEvents)))
+
 	// 解析事件
 	var allEvents []map[string]string
 	for _, raw := range rawEvents {
@@ -159,117 +188,104 @@ func sendEventSummariesAndNotifications(eventType, operator string) {
 		allEvents = append(allEvents, eventMap)
 	}
 
-	// 获取手机号
-	phones, err := RedisClient.SMembers(ctx, phoneKey).Result()
-	if err != nil || len(phones) == 0 {
-		fmt.Printf("❌ 没有找到手机号: %v\n", err)
+	// 获取需要 @ 的人，规则可以只通知不 @ 任何人
+	tokens, err := RedisClient.SMembers(ctx, c.phoneKey).Result()
+	if err != nil {
+		// 不发送缺少 @ 的消息，保留认领稍后重试
+		fmt.Printf("⚠️ 读取去抖桶 %s 的手机号失败，稍后重试: %v\n", c.phoneKey, err)
+		return
+	}
+	parts, mentions, err := renderBucket(c.robot, c.eventType, c.operator, allEvents, tokens)
+	if err != nil {
+		// 保留认领，锁过期后由 recoverOrphanClaims 重新渲染发送
+		fmt.Printf("❌ 去抖桶 %s 渲染失败，稍后重试: %v\n", c.listKey, err)
 		return
 	}
 
-	// 构建消息正文
-	var summaryLines string
-	for _, event := range allEvents {
-		switch eventType {
-		case EventCreate:
-			link := fmt.Sprintf("https://hzbxtx.atlassian.net/browse/%s?linkSource=email", event["summaryKeyID"])
-			summaryLines += fmt.Sprintf("- **摘要名称**: [%s](%s)\n", event["summary"], link)
-
-		case EventDelete:
-			summaryLines += fmt.Sprintf("- **摘要名称**: ~~%s %s~~\n", event["summaryKeyID"], event["summary"])
-
-		case EventUpdateStatus:
-			link := fmt.Sprintf("https://hzbxtx.atlassian.net/browse/%s?linkSource=email", event["summaryKeyID"])
-			staChg := fmt.Sprintf(" **状态**: ~~%s~~ → **%s**", event["statusFrom"], event["statusTo"])
-			summaryLines += fmt.Sprintf("- **摘要名称**: [%s](%s)\n- %s\n", event["summary"], link, staChg)
-
-		case EventUpdateReport:
-			link := fmt.Sprintf("https://hzbxtx.atlassian.net/browse/%s?linkSource=email", event["summaryKeyID"])
-			rptChg := fmt.Sprintf("**报告人**: ~~%s~~ → **%s**", event["rptFrom"], event["rptTo"])
-			summaryLines += fmt.Sprintf("- **摘要名称**: [%s](%s)\n- %s\n", event["summary"], link, rptChg)
+	if !c.held() {
+		fmt.Printf("⚠️ 去抖桶 %s 的锁已失效，放弃发送\n", c.listKey)
+		return
+	}
 
-		case EventUpdateAssigner:
-			link := fmt.Sprintf("https://hzbxtx.atlassian.net/browse/%s?linkSource=email", event["summaryKeyID"])
-			assChg := fmt.Sprintf("**经办人**: %s", event["assignerFromTo"])
-			summaryLines += fmt.Sprintf("- **摘要名称**: [%s](%s)\n- %s\n", event["summary"], link, assChg)
+	// 发送钉钉通知，只在最后一条 @ 相关人员，失败时转入重试队列
+	// 限流时消息在内存中排队，全部发送成功或转入重试队列后才释放认领，
+	// 排队期间进程退出时认领仍保存在 Redis 中，由其他实例或重启后重新发送
+	var pending sync.WaitGroup
+	var lost atomic.Bool
+	saved := func(ok bool) {
+		if !ok {
+			lost.Store(true)
 		}
+		pending.Done()
 	}
-
-	// 构造消息标题
-	var title string
-	switch eventType {
-	case EventCreate:
-		title = "新任务创建"
-	case EventDelete:
-		title = "任务被删除"
-	case EventUpdateReport:
-		title = "任务报告人变更"
-	case EventUpdateAssigner:
-		title = "任务经办人变更"
-	case EventUpdateStatus:
-		title = "任务状态变更"
+	stopRenew := c.keepAlive()
+	for i, content := range parts {
+		if content == "" {
+			continue
+		}
+		pending.Add(1)
+		if i == len(parts)-1 {
+			deliverNotificationThen(c.robot, content, mentions, saved)
+		} else {
+			deliverNotificationThen(c.robot, content, nil, saved)
+		}
 	}
+	pending.Wait()
+	stopRenew()
 
-	mentionText := BuildAtMentions(phones...)
-
-	content := fmt.Sprintf(`
-### **事件通知: %s**             
-%s
-- **操作人**: %s
----
-%s`, title, summaryLines, operator, mentionText)
-
-	// 发送钉钉通知
-	err = sendDingTalkNotification(content, toStringSlice(phones))
-	if err != nil {
-		fmt.Printf("⚠️ 钉钉通知发送失败: %v\n", err)
+	if lost.Load() {
+		// 保留认领，锁过期后由 recoverOrphanClaims 重新发送
+		fmt.Printf("❌ 去抖桶 %s 有消息未能发送或写入重试队列，稍后重试\n", c.listKey)
+		return
 	}
 
-	// 清理 Redis 和 Timer Map
-	_ = RedisClient.Del(ctx, summaryKey).Err()
-	_ = RedisClient.Del(ctx, phoneKey).Err()
-
-	timerLock.Lock()
-	delete(debounceTimers, fmt.Sprintf("timer_key:%s:%s", eventType, operator))
-	timerLock.Unlock()
+	// 清理已发送的数据并释放锁
+	c.release()
 }
 
-func BuildAtMentions(mobiles ...string) string {
-	var mentions string
-	for _, mobile := range mobiles {
-		if mobile != "" {
-			mentions += fmt.Sprintf("@%s ", mobile)
+// renderBucket 渲染去抖桶的消息正文，超长时拆分为多条
+// 自定义模板渲染失败时改用内置模板，内置模板也失败时返回错误
+func renderBucket(robot, eventType, operator string, events []map[string]string, tokens []string) ([]string, []mention, error) {
+	mentions := make([]mention, 0, len(tokens))
+	atIDs := make([]string, 0, len(tokens))
+	for _, t := range tokens {
+		m := parseMention(t)
+		mentions = append(mentions, m)
+		if m.UserID != "" {
+			atIDs = append(atIDs, m.UserID)
+		} else {
+			atIDs = append(atIDs, m.Mobile)
 		}
 	}
-	return mentions
-}
-
-func toStringSlice(items []string) []string {
-	return items
-}
-
-func WithMySQL(config *conf.MySQLConfig, fn func(db *sql.DB) error) error {
-	dsn := fmt.Sprintf("%s:%s@tcp(%s:%d)/%s?charset=utf8mb4&parseTime=true&loc=Local",
-		config.User,
-		config.Password,
-		config.Host,
-		config.Port,
-		config.Database,
-	)
 
-	db, err := sql.Open("mysql", dsn)
+	d := buildDigest(eventType, operator, events, atIDs)
+	parts, err := msgTemplates.RenderParts(robot, d, ding.MaxTextBytes)
 	if err != nil {
-		return fmt.Errorf("打开数据库失败: %v", err)
-	}
-	defer func(db *sql.DB) {
-		err := db.Close()
-		if err != nil {
-
+		fmt.Printf("⚠️ 机器人 %s 的 %s 模板渲染失败，改用内置模板: %v\n", robot, eventType, err)
+		metrics.TemplateRenderFailures.WithLabelValues(robot, eventType).Inc()
+		if parts, err = msgTemplates.RenderBuiltinParts(d, ding.MaxTextBytes); err != nil {
+			return nil, nil, fmt.Errorf("内置模板渲染失败: %v", err)
 		}
-	}(db)
+	}
+	return parts, mentions, nil
+}
 
-	if err := db.Ping(); err != nil {
-		return fmt.Errorf("数据库 Ping 失败: %v", err)
+// withDB 使用启动时创建的连接池访问 MySQL
+func withDB(fn func(db *sql.DB) error) error {
+	if mysqlDB == nil {
+		return fmt.Errorf("数据库未初始化")
 	}
+	return fn(mysqlDB)
+}
 
-	return fn(db)
+// writeDB 与 withDB 相同，失败时按表计入写入失败指标，演练时不写入
+func writeDB(table string, fn func(db *sql.DB) error) error {
+	if dryRun != nil {
+		return nil
+	}
+	err := withDB(fn)
+	if err != nil {
+		metrics.MySQLWriteFailures.WithLabelValues(table).Inc()
+	}
+	return err
 }
@@ -0,0 +1,50 @@
+package handler
+
+import (
+	"whenchangesth/internal/render"
+)
+
+// buildDigest 将去抖桶中的事件转换为模板数据
+func buildDigest(eventType, operator string, events []map[string]string, atIDs []string) render.Digest {
+	d := render.Digest{
+		EventType: eventType,
+		Operator:  operator,
+		Mentions:  atIDs,
+	}
+	for _, event := range events {
+		d.Items = append(d.Items, render.Item{
+			Issue: render.Issue{
+				Key:     event["summaryKeyID"],
+				Summary: event["summary"],
+				Link:    eventLink(event),
+				Status:  event["status"],
+			},
+			Change:  eventChange(eventType, event),
+			Comment: event["comment"],
+		})
+	}
+	return d
+}
+
+// eventChange 取出事件中的字段变更，兼容只写了专用字段的旧数据
+func eventChange(eventType string, event map[string]string) render.Change {
+	change := render.Change{Field: event["field"], From: event["fieldFrom"], To: event["fieldTo"]}
+	if change.Field != "" {
+		return change
+	}
+	switch eventType {
+	case EventUpdateStatus:
+		return render.Change{Field: "status", From: event["statusFrom"], To: event["statusTo"]}
+	case EventUpdateReport:
+		return render.Change{Field: "reporter", From: event["rptFrom"], To: event["rptTo"]}
+	}
+	return change
+}
+
+// eventLink 返回事件写入时生成的链接，旧数据没有链接时按配置重新生成
+func eventLink(event map[string]string) string {
+	if link := event["link"]; link != "" {
+		return link
+	}
+	return issueLink(event["summaryKeyID"], "")
+}
@@ -58,16 +58,16 @@ type EventHandler func(interface{}) error
 // eventHandlers 是事件类型到处理器的映射表
 var eventHandlers = map[pkg.Event]EventHandler{
 	//pkg.StatusTransitionEvent: handleStatusTransition,
-	pkg.IssueCreatedEvent: handleIssueCreated,
-	pkg.IssueDeletedEvent: handleIssueDeleted,
-	pkg.IssueUpdatedEvent: handleIssueUpdated,
-	//pkg.IssueWorkLogEvent:               handleIssueWorkLog,
-	//pkg.WorkLogCreatedEvent:             handleWorkLogCreated,
-	//pkg.WorkLogUpdatedEvent:             handleWorkLogUpdated,
-	//pkg.WorkLogDeletedEvent:             handleWorkLogDeleted,
-	//pkg.CommentCreatedEvent: handleCommentCreated,
-	//pkg.CommentUpdatedEvent:             handleCommentUpdated,
-	//pkg.CommentDeletedEvent:             handleCommentDeleted,
+	pkg.IssueCreatedEvent:   handleIssueCreated,
+	pkg.IssueDeletedEvent:   handleIssueDeleted,
+	pkg.IssueUpdatedEvent:   handleIssueUpdated,
+	pkg.IssueWorkLogEvent:   handleIssueWorkLog,
+	pkg.WorkLogCreatedEvent: handleWorkLog,
+	pkg.WorkLogUpdatedEvent: handleWorkLog,
+	pkg.WorkLogDeletedEvent: handleWorkLog,
+	pkg.CommentCreatedEvent: handleComment,
+	pkg.CommentUpdatedEvent: handleComment,
+	pkg.CommentDeletedEvent: handleComment,
 	//pkg.LinkCreatedEvent:                handleLinkCreated,
 	//pkg.LinkDeletedEvent:                handleLinkDeleted,
 	//pkg.UserCreatedEvent:                handleUserCreated,
@@ -85,13 +85,13 @@ var eventHandlers = map[pkg.Event]EventHandler{
 	//pkg.SprintCreatedEvent:              handleSprintCreated,
 	//pkg.SprintUpdatedEvent:              handleSprintUpdated,
 	//pkg.SprintDeletedEvent:              handleSprintDeleted,
-	//pkg.SprintStartedEvent:              handleSprintStarted,
-	//pkg.SprintClosedEvent:               handleSprintClosed,
+	pkg.SprintStartedEvent: handleSprint,
+	pkg.SprintClosedEvent:  handleSprint,
 	//pkg.VersionCreatedEvent:             handleVersionCreated,
 	//pkg.VersionUpdatedEvent:             handleVersionUpdated,
 	//pkg.VersionDeletedEvent:             handleVersionDeleted,
-	//pkg.VersionReleasedEvent:            handleVersionReleased,
-	//pkg.VersionUnreleasedEvent:          handleVersionUnreleased,
+	pkg.VersionReleasedEvent:   handleVersion,
+	pkg.VersionUnreleasedEvent: handleVersion,
 	//pkg.OptionTimeTrackingChangedEvent:  handleOptionTimeTrackingChanged,
 	//pkg.OptionIssueLinksChangedEvent:    handleOptionIssueLinksChanged,
 	//pkg.OptionSubTasksChangedEvent:      handleOptionSubTasksChanged,
@@ -12,31 +12,6 @@ func handleIssueCreated(payload interface{}) error {
 		return fmt.Errorf("invalid payload type for issue created: %T", payload)
 	}
 
-	if len(pl.ChangeLog.Items) == 0 {
-		return nil
-	}
-
-	for _, item := range pl.ChangeLog.Items {
-		if item.Field != "assignee" {
-			continue
-		}
-
-		fields := pl.Issue.Fields
-		assigneeNumber := getPhoneNumberWithFallback(fields.Assignee.DisplayName)
-		reporterNumber := getPhoneNumberWithFallback(fields.Reporter.DisplayName)
-
-		args := &eventArgs{
-			eventType:     EventCreate,
-			summaryKeyID:  pl.Issue.Key,
-			operator:      pl.User.DisplayName,
-			assigneePhone: assigneeNumber,
-			reporterPhone: reporterNumber,
-			summary:       fields.Summary,
-		}
-
-		PushEventArgumentsAndPhones(args)
-
-	}
-
-	return nil
+	e, _ := issueEventOf(pl)
+	return processIssueEvent(e)
 }
@@ -12,20 +12,6 @@ func handleIssueDeleted(payload interface{}) error {
 		return errors.New("invalid payload type for issue deleted")
 	}
 
-	fields := pl.Issue.Fields
-	assigneeNumber := getPhoneNumberWithFallback(fields.Assignee.DisplayName)
-	reporterNumber := getPhoneNumberWithFallback(fields.Reporter.DisplayName)
-
-	args := &eventArgs{
-		eventType:     EventDelete,
-		summaryKeyID:  pl.Issue.Key,
-		operator:      pl.User.DisplayName,
-		assigneePhone: assigneeNumber,
-		reporterPhone: reporterNumber,
-		summary:       fields.Summary,
-	}
-
-	PushEventArgumentsAndPhones(args)
-
-	return nil
+	e, _ := issueEventOf(pl)
+	return processIssueEvent(e)
 }
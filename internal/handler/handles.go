@@ -3,23 +3,82 @@ package handler
 import (
 	"fmt"
 	"log"
+	"strings"
+	"time"
 	"whenchangesth/internal/conf"
-
-	"github.com/youxihu/dingtalk/dingtalk"
+	"whenchangesth/internal/ding"
+	"whenchangesth/internal/metrics"
+	"whenchangesth/internal/objects"
 )
 
 const (
 	Title = "JIRA事件通知"
 )
 
-// getPhoneNumberWithFallback 获取电话号码，如果失败则返回空字符串
-func getPhoneNumberWithFallback(displayName string) string {
-	phoneNumber, err := conf.GetPhoneNumber(displayName)
-	if err != nil {
-		log.Printf("Error getting phone number for %s: %v", displayName, err)
+// mention 是消息中需要 @ 的人，已知钉钉 userId 时优先使用 userId
+type mention struct {
+	Mobile string
+	UserID string
+}
+
+const (
+	mentionUserPrefix   = "uid:"
+	mentionMobilePrefix = "mobile:"
+)
+
+// token 将 mention 编码为存入 Redis Set 的字符串
+func (m mention) token() string {
+	if m.UserID != "" {
+		return mentionUserPrefix + m.UserID
+	}
+	if m.Mobile != "" {
+		return mentionMobilePrefix + m.Mobile
+	}
+	return ""
+}
+
+// parseMention 解析 Redis Set 中的字符串，不带前缀的旧数据按手机号处理
+func parseMention(token string) mention {
+	switch {
+	case strings.HasPrefix(token, mentionUserPrefix):
+		return mention{UserID: strings.TrimPrefix(token, mentionUserPrefix)}
+	case strings.HasPrefix(token, mentionMobilePrefix):
+		return mention{Mobile: strings.TrimPrefix(token, mentionMobilePrefix)}
+	default:
+		return mention{Mobile: token}
+	}
+}
+
+// lookupPerson 在通讯录中查找 Jira 用户对应的人
+func lookupPerson(u *objects.User) (*conf.Person, bool) {
+	if u == nil {
+		return nil, false
+	}
+	return phoneBook.Resolve(conf.UserRef{
+		AccountID:   u.AccountID,
+		Key:         u.Key,
+		Name:        u.Name,
+		Email:       u.EmailAddress,
+		DisplayName: u.DisplayName,
+	})
+}
+
+// getPhoneNumberWithFallback 获取电话号码，如果未找到则返回空字符串
+func getPhoneNumberWithFallback(u *objects.User) string {
+	p, ok := lookupPerson(u)
+	if !ok {
 		return ""
 	}
-	return phoneNumber
+	return p.Phone
+}
+
+// mentionOf 返回 @ 某个 Jira 用户所需的信息，未找到时返回空 mention
+func mentionOf(u *objects.User) mention {
+	p, ok := lookupPerson(u)
+	if !ok {
+		return mention{}
+	}
+	return mention{Mobile: p.Phone, UserID: p.DingUserID}
 }
 
 // buildAtMobiles 构建需要@的手机号列表
@@ -34,9 +93,26 @@ func buildAtMobiles(assignee, reporter string) []string {
 	return atMobiles
 }
 
-// sendDingTalkNotification 发送钉钉通知
-func sendDingTalkNotification(content string, atMobiles []string) error {
-	return dingtalk.SendDingDingNotification(dingCfg.Token, dingCfg.Secret, Title, content, atMobiles, false)
+// sendDingTalkNotification 通过指定机器人发送钉钉通知
+func sendDingTalkNotification(robot, content string, mentions []mention) error {
+	bot, ok := appCfg.Robot(robot)
+	if !ok {
+		return fmt.Errorf("未定义的机器人: %s", robot)
+	}
+
+	msg := ding.Message{Title: Title, Text: content}
+	for _, m := range mentions {
+		if m.UserID != "" {
+			msg.AtUserIds = append(msg.AtUserIds, m.UserID)
+		} else if m.Mobile != "" {
+			msg.AtMobiles = append(msg.AtMobiles, m.Mobile)
+		}
+	}
+	start := time.Now()
+	err := ding.Send(bot.Token, bot.Secret, msg)
+	metrics.DingTalkSendDuration.WithLabelValues(robot).Observe(time.Since(start).Seconds())
+	metrics.DingTalkSends.WithLabelValues(robot, metrics.SendErrcode(err)).Inc()
+	return err
 }
 
 // handleError 统一错误处理
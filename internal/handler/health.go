@@ -0,0 +1,140 @@
+package handler
+
+import (
+	"context"
+	"fmt"
+	"log"
+	"net/http"
+	"time"
+
+	"github.com/gin-gonic/gin"
+)
+
+const (
+	checkTimeout        = 2 * time.Second // 单项依赖检查的超时时间
+	recoverRetryBackoff = 5 * time.Second // Redis 不可用时重试启动恢复的间隔
+)
+
+// checkResult 是单项依赖的检查结果
+type checkResult struct {
+	Status    string      `json:"status"`
+	LatencyMS float64     `json:"latency_ms"`
+	Error     string      `json:"error,omitempty"`
+	Detail    interface{} `json:"detail,omitempty"`
+}
+
+// timedCheck 执行一项检查并记录耗时
+func timedCheck(check func(ctx context.Context) error) checkResult {
+	c, cancel := context.WithTimeout(ctx, checkTimeout)
+	defer cancel()
+
+	start := time.Now()
+	err := check(c)
+	r := checkResult{Status: "ok", LatencyMS: float64(time.Since(start).Microseconds()) / 1000}
+	if err != nil {
+		r.Status, r.Error = "down", err.Error()
+	}
+	return r
+}
+
+// readinessChecks 检查 Redis、MySQL、通讯录、钉钉机器人配置和 webhook 处理队列
+func readinessChecks() map[string]checkResult {
+	checks := make(map[string]checkResult, 5)
+
+	checks["redis"] = timedCheck(func(c context.Context) error {
+		return RedisClient.Ping(c).Err()
+	})
+	checks["mysql"] = timedCheck(func(c context.Context) error {
+		if mysqlDB == nil {
+			return fmt.Errorf("数据库未初始化")
+		}
+		if err := mysqlDB.PingContext(c); err != nil {
+			return err
+		}
+		// 自动迁移尚未完成时缺少表或字段，同样视为不可用
+		if err := migrationPending.Load(); err != nil {
+			return *err
+		}
+		return nil
+	})
+
+	// 通讯录重新加载失败时继续使用上一份数据，只在从未加载成功时视为不可用
+	phone := checkResult{Status: "ok"}
+	if phoneBook == nil {
+		phone.Status, phone.Error = "down", "通讯录未加载"
+	} else {
+		stats := phoneBook.Stats()
+		phone.Detail = stats
+		if stats.Reloads == 0 {
+			phone.Status, phone.Error = "down", stats.LastError
+		}
+	}
+	checks["phonebook"] = phone
+
+	ding := checkResult{Status: "ok"}
+	if appCfg == nil || msgTemplates == nil {
+		ding.Status, ding.Error = "down", "机器人配置或消息模板未加载"
+	} else {
+		ding.Detail = gin.H{"robots": appCfg.RobotNames()}
+	}
+	checks["dingtalk"] = ding
+
+	// 处理队列已满时新的 webhook 会被拒绝
+	queue := checkResult{Status: "ok"}
+	if webhookPool == nil {
+		queue.Status, queue.Error = "down", "webhook 处理队列未启动"
+	} else {
+		queued, capacity := webhookPool.depth()
+		queue.Detail = gin.H{"queued": queued, "capacity": capacity}
+		if queued >= capacity {
+			queue.Status, queue.Error = "saturated", "webhook 处理队列已满"
+		}
+	}
+	checks["webhook_queue"] = queue
+
+	return checks
+}
+
+// HealthzHandler 用于存活检查，进程能够响应即返回 200
+func HealthzHandler(c *gin.Context) {
+	c.JSON(http.StatusOK, gin.H{"status": "ok"})
+}
+
+// ReadyzHandler 用于就绪检查，任一依赖不可用时返回 503 和降级状态
+func ReadyzHandler(c *gin.Context) {
+	checks := readinessChecks()
+	status, code := "ok", http.StatusOK
+	for _, r := range checks {
+		if r.Status != "ok" {
+			status, code = "degraded", http.StatusServiceUnavailable
+		}
+	}
+	c.JSON(code, gin.H{"status": status, "checks": checks})
+}
+
+// recoverOnBoot 恢复上次退出时未发送的数据，Redis 不可用时定期重试，直到恢复成功
+func recoverOnBoot(ctx context.Context) {
+	for {
+		err := recoverState()
+		if err == nil {
+			return
+		}
+		log.Printf("⚠️ 启动恢复失败，%v 后重试: %v", recoverRetryBackoff, err)
+		select {
+		case <-ctx.Done():
+			return
+		case <-time.After(recoverRetryBackoff):
+		}
+	}
+}
+
+// recoverState 恢复未发送的去抖桶、认领和重试消息
+func recoverState() error {
+	if err := recoverOrphanBuckets(0); err != nil {
+		return err
+	}
+	if err := recoverOrphanClaims(); err != nil {
+		return err
+	}
+	return recoverOrphanRetries()
+}
@@ -0,0 +1,53 @@
+package handler
+
+import (
+	"encoding/json"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+
+	"github.com/gin-gonic/gin"
+	"github.com/go-redis/redis/v8"
+)
+
+func TestReadyzReportsDegradedDependencies(t *testing.T) {
+	gin.SetMode(gin.TestMode)
+	prevRedis, prevDB := RedisClient, mysqlDB
+	defer func() { RedisClient, mysqlDB = prevRedis, prevDB }()
+
+	// 端口 1 上没有 Redis，Ping 会很快失败
+	RedisClient = redis.NewClient(&redis.Options{Addr: "127.0.0.1:1", MaxRetries: -1})
+	defer RedisClient.Close()
+	mysqlDB = nil
+
+	r := gin.New()
+	r.GET("/readyz", ReadyzHandler)
+	r.GET("/healthz", HealthzHandler)
+
+	w := httptest.NewRecorder()
+	r.ServeHTTP(w, httptest.NewRequest(http.MethodGet, "/readyz", nil))
+	if w.Code != http.StatusServiceUnavailable {
+		t.Fatalf("readyz code = %d, want 503", w.Code)
+	}
+	var body struct {
+		Status string                 `json:"status"`
+		Checks map[string]checkResult `json:"checks"`
+	}
+	if err := json.Unmarshal(w.Body.Bytes(), &body); err != nil {
+		t.Fatal(err)
+	}
+	if body.Status != "degraded" {
+		t.Errorf("status = %q, want degraded", body.Status)
+	}
+	for _, name := range []string{"redis", "mysql"} {
+		if c := body.Checks[name]; c.Status != "down" || c.Error == "" {
+			t.Errorf("%s check = %+v, want down with error", name, c)
+		}
+	}
+
+	w = httptest.NewRecorder()
+	r.ServeHTTP(w, httptest.NewRequest(http.MethodGet, "/healthz", nil))
+	if w.Code != http.StatusOK {
+		t.Errorf("healthz code = %d, want 200", w.Code)
+	}
+}
@@ -0,0 +1,230 @@
+package handler
+
+import (
+	"database/sql"
+	"fmt"
+	"log"
+	"net/http"
+	"regexp"
+	"strconv"
+	"strings"
+	"time"
+	"whenchangesth/internal/render"
+
+	"github.com/gin-gonic/gin"
+)
+
+// storedEvent 是 jirahook_eventdata 中的一条事件，不包含手机号
+type storedEvent struct {
+	ID        int64     `json:"id"`
+	EventType string    `json:"event_type"`
+	IssueKey  string    `json:"issue_key"`
+	Summary   string    `json:"summary"`
+	Operator  string    `json:"operator"`
+	Status    string    `json:"status"`
+	Field     string    `json:"field,omitempty"`
+	From      string    `json:"from,omitempty"`
+	To        string    `json:"to,omitempty"`
+	CreatedAt time.Time `json:"created_at"`
+}
+
+const storedEventColumns = `id, event_type, summary_key_id, summary, operator, status,
+    field, field_from, field_to, status_from, status_to, rpt_from, rpt_to, assigner_from_to, created_at`
+
+// scanStoredEvent 读取一行事件，兼容只写了专用字段的旧数据
+func scanStoredEvent(row interface{ Scan(...interface{}) error }) (storedEvent, error) {
+	var e storedEvent
+	var field, fieldFrom, fieldTo, statusFrom, statusTo, rptFrom, rptTo, assignerFromTo string
+	if err := row.Scan(&e.ID, &e.EventType, &e.IssueKey, &e.Summary, &e.Operator, &e.Status,
+		&field, &fieldFrom, &fieldTo, &statusFrom, &statusTo, &rptFrom, &rptTo, &assignerFromTo, &e.CreatedAt); err != nil {
+		return e, err
+	}
+	change := storedChange(e.EventType, map[string]string{
+		"field": field, "fieldFrom": fieldFrom, "fieldTo": fieldTo,
+		"statusFrom": statusFrom, "statusTo": statusTo,
+		"rptFrom": rptFrom, "rptTo": rptTo,
+		"assignerFromTo": assignerFromTo,
+	})
+	e.Field, e.From, e.To = change.Field, change.From, change.To
+	return e, nil
+}
+
+// storedChange 取出事件的字段变更，旧数据的经办人变更只保存了渲染后的文本
+func storedChange(eventType string, event map[string]string) render.Change {
+	change := eventChange(eventType, event)
+	if change.Field == "" && eventType == EventUpdateAssigner {
+		change = render.Change{Field: "assignee"}
+		change.From, change.To = parseAssignerFromTo(event["assignerFromTo"])
+	}
+	return change
+}
+
+// assignerFromToPattern 匹配 "~~张三~~ → **李四**" 或 "→ **李四**"
+var assignerFromToPattern = regexp.MustCompile(`^(?:~~(.*)~~ )?→ \*\*(.*)\*\*$`)
+
+// parseAssignerFromTo 解析旧数据中经办人变更的文本
+func parseAssignerFromTo(s string) (from, to string) {
+	m := assignerFromToPattern.FindStringSubmatch(s)
+	if m == nil {
+		return "", s
+	}
+	return m[1], m[2]
+}
+
+// eventFilter 是事件列表的查询条件
+type eventFilter struct {
+	issueKey, operator, eventType, status string
+	since, until                          time.Time
+	before                                int64
+	limit                                 int
+}
+
+// parseEventFilter 从查询参数中解析查询条件
+func parseEventFilter(c *gin.Context) (eventFilter, error) {
+	f := eventFilter{
+		issueKey:  c.Query("issue"),
+		operator:  c.Query("operator"),
+		eventType: c.Query("event_type"),
+		status:    c.Query("status"),
+	}
+	var err error
+	if f.limit, err = strconv.Atoi(c.DefaultQuery("limit", "50")); err != nil || f.limit <= 0 || f.limit > 500 {
+		return f, fmt.Errorf("limit must be between 1 and 500")
+	}
+	if s := c.Query("before"); s != "" {
+		if f.before, err = strconv.ParseInt(s, 10, 64); err != nil || f.before <= 0 {
+			return f, fmt.Errorf("before must be a positive event id")
+		}
+	}
+	if f.since, err = parseQueryTime(c.Query("since")); err != nil {
+		return f, fmt.Errorf("invalid since: %v", err)
+	}
+	if f.until, err = parseQueryTime(c.Query("until")); err != nil {
+		return f, fmt.Errorf("invalid until: %v", err)
+	}
+	return f, nil
+}
+
+// parseQueryTime 解析 RFC3339 时间或本地日期，空字符串返回零值
+func parseQueryTime(s string) (time.Time, error) {
+	if s == "" {
+		return time.Time{}, nil
+	}
+	if t, err := time.Parse(time.RFC3339, s); err == nil {
+		return t, nil
+	}
+	return time.ParseInLocation(time.DateOnly, s, time.Local)
+}
+
+// where 返回查询条件对应的 WHERE 子句和参数
+func (f eventFilter) where() (string, []interface{}) {
+	var conds []string
+	var args []interface{}
+	add := func(cond string, arg interface{}) {
+		conds = append(conds, cond)
+		args = append(args, arg)
+	}
+	if f.issueKey != "" {
+		add("summary_key_id = ?", f.issueKey)
+	}
+	if f.operator != "" {
+		add("operator = ?", f.operator)
+	}
+	if f.eventType != "" {
+		add("event_type = ?", f.eventType)
+	}
+	if f.status != "" {
+		add("status = ?", f.status)
+	}
+	if !f.since.IsZero() {
+		add("created_at >= ?", f.since)
+	}
+	if !f.until.IsZero() {
+		add("created_at < ?", f.until)
+	}
+	if f.before > 0 {
+		add("id < ?", f.before)
+	}
+	if len(conds) == 0 {
+		return "", nil
+	}
+	return " WHERE " + strings.Join(conds, " AND "), args
+}
+
+// queryEvents 按条件查询事件，按时间倒序
+func queryEvents(query string, args ...interface{}) ([]storedEvent, error) {
+	events := []storedEvent{}
+	err := withDB(func(db *sql.DB) error {
+		rows, err := db.Query(query, args...)
+		if err != nil {
+			return err
+		}
+		defer rows.Close()
+		for rows.Next() {
+			e, err := scanStoredEvent(rows)
+			if err != nil {
+				return err
+			}
+			events = append(events, e)
+		}
+		return rows.Err()
+	})
+	return events, err
+}
+
+// EventsHandler 分页列出保存的事件，较新的在前，用返回的 next_before 翻页
+func EventsHandler(c *gin.Context) {
+	f, err := parseEventFilter(c)
+	if err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+		return
+	}
+	where, args := f.where()
+	events, err := queryEvents(`SELECT `+storedEventColumns+` FROM jirahook_eventdata`+where+
+		` ORDER BY id DESC LIMIT ?`, append(args, f.limit)...)
+	if err != nil {
+		log.Printf("Failed to list events: %v", err)
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to list events"})
+		return
+	}
+
+	resp := gin.H{"events": events}
+	if len(events) == f.limit {
+		resp["next_before"] = events[len(events)-1].ID
+	}
+	c.JSON(http.StatusOK, resp)
+}
+
+// timelineEntry 是问题历史中的一次状态、经办人或报告人变更
+type timelineEntry struct {
+	Time     time.Time `json:"time"`
+	Operator string    `json:"operator"`
+	Field    string    `json:"field"`
+	From     string    `json:"from"`
+	To       string    `json:"to"`
+}
+
+// IssueTimelineHandler 按时间顺序返回问题的状态、经办人和报告人变更
+func IssueTimelineHandler(c *gin.Context) {
+	key := c.Param("key")
+	events, err := queryEvents(`SELECT `+storedEventColumns+` FROM jirahook_eventdata
+        WHERE summary_key_id = ? AND event_type IN (?, ?, ?)
+        ORDER BY created_at, id`, key, EventUpdateStatus, EventUpdateAssigner, EventUpdateReport)
+	if err != nil {
+		log.Printf("Failed to load timeline of %s: %v", key, err)
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to load timeline"})
+		return
+	}
+
+	timeline := make([]timelineEntry, 0, len(events))
+	for _, e := range events {
+		timeline = append(timeline, timelineEntry{
+			Time:     e.CreatedAt,
+			Operator: e.Operator,
+			Field:    e.Field,
+			From:     e.From,
+			To:       e.To,
+		})
+	}
+	c.JSON(http.StatusOK, gin.H{"issue": key, "timeline": timeline})
+}
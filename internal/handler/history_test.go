@@ -0,0 +1,50 @@
+package handler
+
+import (
+	"reflect"
+	"testing"
+	"time"
+	"whenchangesth/internal/render"
+)
+
+func TestStoredChange(t *testing.T) {
+	cases := []struct {
+		name      string
+		eventType string
+		event     map[string]string
+		want      render.Change
+	}{
+		{"assignee", EventUpdateAssigner,
+			map[string]string{"field": "assignee", "fieldFrom": "张三", "fieldTo": "李四"},
+			render.Change{Field: "assignee", From: "张三", To: "李四"}},
+		{"legacy assignee", EventUpdateAssigner,
+			map[string]string{"assignerFromTo": "~~张三~~ → **李四**"},
+			render.Change{Field: "assignee", From: "张三", To: "李四"}},
+		{"legacy first assignee", EventUpdateAssigner,
+			map[string]string{"assignerFromTo": "→ **李四**"},
+			render.Change{Field: "assignee", To: "李四"}},
+		{"legacy status", EventUpdateStatus,
+			map[string]string{"statusFrom": "进行中", "statusTo": "完成"},
+			render.Change{Field: "status", From: "进行中", To: "完成"}},
+	}
+	for _, c := range cases {
+		if got := storedChange(c.eventType, c.event); got != c.want {
+			t.Errorf("%s: got %+v, want %+v", c.name, got, c.want)
+		}
+	}
+}
+
+func TestEventFilterWhere(t *testing.T) {
+	since := time.Date(2024, 5, 1, 0, 0, 0, 0, time.Local)
+	where, args := eventFilter{issueKey: "ABC-1", status: "完成", since: since, before: 100}.where()
+	if want := " WHERE summary_key_id = ? AND status = ? AND created_at >= ? AND id < ?"; where != want {
+		t.Errorf("where = %q, want %q", where, want)
+	}
+	if want := []interface{}{"ABC-1", "完成", since, int64(100)}; !reflect.DeepEqual(args, want) {
+		t.Errorf("args = %v, want %v", args, want)
+	}
+
+	if where, args := (eventFilter{}).where(); where != "" || args != nil {
+		t.Errorf("empty filter = %q %v", where, args)
+	}
+}
@@ -0,0 +1,320 @@
+package handler
+
+import (
+	"fmt"
+	"slices"
+	"whenchangesth/internal/conf"
+	"whenchangesth/internal/objects"
+	"whenchangesth/internal/rules"
+	"whenchangesth/pkg"
+)
+
+// issueEvent 是问题类事件的公共部分，评论事件同时带有 comment
+type issueEvent struct {
+	event     string
+	user      *objects.User
+	issue     *objects.Issue
+	changeLog *objects.ChangeLog
+	comment   *objects.Comment
+}
+
+// issueEventOf 从解析后的 payload 中取出问题类事件
+func issueEventOf(payload interface{}) (issueEvent, bool) {
+	switch pl := payload.(type) {
+	case pkg.IssueCreatedPayload:
+		return issueEvent{event: rules.EventIssueCreated, user: pl.User, issue: pl.Issue, changeLog: pl.ChangeLog}, true
+	case pkg.IssueDeletedPayload:
+		return issueEvent{event: rules.EventIssueDeleted, user: pl.User, issue: pl.Issue, changeLog: pl.ChangeLog}, true
+	case pkg.IssueUpdatedPayload:
+		return issueEvent{event: rules.EventIssueUpdated, user: pl.User, issue: pl.Issue, changeLog: pl.ChangeLog}, true
+	case pkg.IssueAssignedPayload:
+		return issueEvent{event: rules.EventIssueUpdated, user: pl.User, issue: pl.Issue, changeLog: pl.ChangeLog}, true
+	case pkg.IssueGenericPayload:
+		return issueEvent{event: rules.EventIssueUpdated, user: pl.User, issue: pl.Issue, changeLog: pl.ChangeLog}, true
+
+	// 评论: 问题更新事件中的评论动作与独立的评论事件按同一事件名处理
+	case pkg.IssueCommentCreatedPayload:
+		return issueEvent{event: rules.EventCommentCreated, user: pl.User, issue: pl.Issue, comment: pl.Comment}, true
+	case pkg.IssueCommentUpdatedPayload:
+		return issueEvent{event: rules.EventCommentUpdated, user: pl.User, issue: pl.Issue, comment: pl.Comment}, true
+	case pkg.IssueCommentDeletedPayload:
+		return issueEvent{event: rules.EventCommentDeleted, user: pl.User, issue: pl.Issue, comment: pl.Comment}, true
+	case pkg.CommentCreatedPayload:
+		return issueEvent{event: rules.EventCommentCreated, user: commentAuthor(pl.Comment), issue: pl.Issue, comment: pl.Comment}, true
+	case pkg.CommentUpdatedPayload:
+		return issueEvent{event: rules.EventCommentUpdated, user: commentAuthor(pl.Comment), issue: pl.Issue, comment: pl.Comment}, true
+	case pkg.CommentDeletedPayload:
+		return issueEvent{event: rules.EventCommentDeleted, user: commentAuthor(pl.Comment), issue: pl.Issue, comment: pl.Comment}, true
+	default:
+		return issueEvent{}, false
+	}
+}
+
+// fields 返回问题字段，缺失时返回空结构避免空指针
+func (e issueEvent) fields() *objects.IssueFields {
+	if e.issue == nil || e.issue.Fields == nil {
+		return &objects.IssueFields{}
+	}
+	return e.issue.Fields
+}
+
+// items 返回变更记录
+func (e issueEvent) items() []*objects.ChangeLogItem {
+	if e.changeLog == nil {
+		return nil
+	}
+	return e.changeLog.Items
+}
+
+// baseFacts 返回与变更记录无关的规则输入
+func (e issueEvent) baseFacts() rules.Facts {
+	fields := e.fields()
+	f := rules.Facts{Event: e.event, Labels: fields.Labels}
+	if fields.Project != nil {
+		f.Project = fields.Project.Key
+	}
+	if fields.Priority != nil {
+		f.Priority = fields.Priority.Name
+	}
+	if fields.Type != nil {
+		f.IssueType = fields.Type.Name
+	}
+	return f
+}
+
+// itemFacts 返回某条变更记录对应的规则输入
+func (e issueEvent) itemFacts(item *objects.ChangeLogItem) rules.Facts {
+	f := e.baseFacts()
+	f.Field = item.Field
+	f.From = item.FromString
+	f.To = item.ToString
+	return f
+}
+
+// issueDecision 是一条待发送通知及其规则评估结果
+type issueDecision struct {
+	eventType string
+	item      *objects.ChangeLogItem
+	facts     []rules.Facts
+	decision  rules.Decision
+	robots    []string
+}
+
+// decideIssueEvent 评估规则
+// 创建和删除事件按整个事件评估一次，更新事件按每条变更记录分别评估
+func decideIssueEvent(cfg *conf.Config, e issueEvent) []issueDecision {
+	rs := cfg.EffectiveRules()
+
+	var decisions []issueDecision
+	switch e.event {
+	case rules.EventCommentCreated, rules.EventCommentUpdated, rules.EventCommentDeleted:
+		f := e.baseFacts()
+		decisions = append(decisions, issueDecision{
+			eventType: commentEventTypes[e.event],
+			facts:     []rules.Facts{f},
+			decision:  rules.Evaluate(rs, f),
+		})
+
+	case rules.EventIssueCreated, rules.EventIssueDeleted:
+		eventType := EventCreate
+		if e.event == rules.EventIssueDeleted {
+			eventType = EventDelete
+		}
+
+		facts := []rules.Facts{e.baseFacts()}
+		if items := e.items(); len(items) > 0 {
+			facts = facts[:0]
+			for _, item := range items {
+				facts = append(facts, e.itemFacts(item))
+			}
+		}
+		var results []rules.Decision
+		for _, f := range facts {
+			results = append(results, rules.Evaluate(rs, f))
+		}
+		decisions = append(decisions, issueDecision{
+			eventType: eventType,
+			facts:     facts,
+			decision:  rules.Merge(results...),
+		})
+
+	default:
+		for _, item := range e.items() {
+			f := e.itemFacts(item)
+			decisions = append(decisions, issueDecision{
+				eventType: fieldEventType(item.Field),
+				item:      item,
+				facts:     []rules.Facts{f},
+				decision:  rules.Evaluate(rs, f),
+			})
+		}
+	}
+
+	for i := range decisions {
+		d := &decisions[i]
+		if !d.decision.Notify {
+			continue
+		}
+		d.robots = d.decision.Robots
+		if len(d.robots) == 0 {
+			d.robots = routeRobots(cfg.Routes, e.issue)
+		}
+	}
+	return decisions
+}
+
+// fieldEventType 返回字段变更对应的消息类型
+func fieldEventType(field string) string {
+	switch field {
+	case "status":
+		return EventUpdateStatus
+	case "reporter":
+		return EventUpdateReport
+	case "assignee":
+		return EventUpdateAssigner
+	default:
+		return EventUpdateField
+	}
+}
+
+// processIssueEvent 记录问题快照和每条事件，并按规则评估结果将需要通知的事件写入去抖桶
+func processIssueEvent(e issueEvent) error {
+	if err := saveIssueSnapshot(e); err != nil {
+		fmt.Printf("❌ %v\n", err)
+	}
+
+	for _, d := range decideIssueEvent(appCfg, e) {
+		args := e.args(d)
+		saveEventData(args)
+		if d.decision.Notify {
+			PushEventArgumentsAndPhones(args)
+		}
+	}
+	return nil
+}
+
+// args 构造写入去抖桶的事件参数
+func (e issueEvent) args(d issueDecision) *eventArgs {
+	fields := e.fields()
+	args := &eventArgs{
+		eventType:     d.eventType,
+		operator:      userName(e.user),
+		assigneePhone: getPhoneNumberWithFallback(fields.Assignee),
+		reporterPhone: getPhoneNumberWithFallback(fields.Reporter),
+		summary:       fields.Summary,
+		mentions:      roleMentions(d.decision.Mention, e.user, fields),
+		robots:        d.robots,
+		immediate:     d.decision.Immediate,
+	}
+	if e.issue != nil {
+		args.summaryKeyID = e.issue.Key
+		args.link = issueLink(e.issue.Key, e.issue.Self)
+	}
+	if fields.Status != nil {
+		args.status = fields.Status.Name
+	}
+	if e.comment != nil {
+		args.comment = commentText(e.comment.Body)
+		if slices.Contains(d.decision.Mention, rules.RoleMentioned) {
+			args.mentions = appendMentions(args.mentions, commentMentions(e.comment.Body)...)
+		}
+	}
+
+	item := d.item
+	if item == nil {
+		return args
+	}
+	args.field = item.Field
+	args.fieldFrom = item.FromString
+	args.fieldTo = item.ToString
+	switch d.eventType {
+	case EventUpdateReport:
+		args.rptFrom = item.FromString
+		args.rptTo = item.ToString
+	case EventUpdateAssigner:
+		args.assignerFromTo = fmt.Sprintf("→ **%s**", item.ToString)
+		if item.FromString != "" {
+			args.assignerFromTo = fmt.Sprintf("~~%s~~ → **%s**", item.FromString, item.ToString)
+		}
+	case EventUpdateStatus:
+		args.statusFrom = item.FromString
+		args.statusTo = item.ToString
+	}
+	return args
+}
+
+// userName 返回用户的显示名称
+func userName(u *objects.User) string {
+	if u == nil {
+		return ""
+	}
+	return u.DisplayName
+}
+
+// roleMentions 将规则中的角色解析为需要 @ 的人
+func roleMentions(roles []string, operator *objects.User, fields *objects.IssueFields) []mention {
+	var users []*objects.User
+	for _, role := range roles {
+		switch role {
+		case rules.RoleAssignee:
+			users = append(users, fields.Assignee)
+		case rules.RoleReporter:
+			users = append(users, fields.Reporter)
+		case rules.RoleCreator:
+			users = append(users, fields.Creator)
+		case rules.RoleOperator:
+			users = append(users, operator)
+		case rules.RoleWatchers:
+			if fields.Watches != nil {
+				users = append(users, fields.Watches.Watchers...)
+			}
+		}
+	}
+
+	var mentions []mention
+	for _, u := range users {
+		mentions = appendMentions(mentions, mentionOf(u))
+	}
+	return mentions
+}
+
+// appendMentions 追加 mention，跳过空值和重复项
+func appendMentions(dst []mention, items ...mention) []mention {
+	for _, m := range items {
+		t := m.token()
+		if t == "" {
+			continue
+		}
+		if !slices.ContainsFunc(dst, func(d mention) bool { return d.token() == t }) {
+			dst = append(dst, m)
+		}
+	}
+	return dst
+}
+
+// RuleTrace 是一次规则评估的结果，供 rules 子命令输出
+type RuleTrace struct {
+	EventType string
+	Facts     []rules.Facts
+	Decision  rules.Decision
+	Robots    []string
+}
+
+// TraceRules 对解析后的 payload 评估规则，不写入 Redis 也不发送通知
+func TraceRules(cfg *conf.Config, payload interface{}) ([]RuleTrace, error) {
+	e, ok := issueEventOf(payload)
+	if !ok {
+		return nil, fmt.Errorf("unsupported payload type for rules: %T", payload)
+	}
+
+	var traces []RuleTrace
+	for _, d := range decideIssueEvent(cfg, e) {
+		traces = append(traces, RuleTrace{
+			EventType: d.eventType,
+			Facts:     d.facts,
+			Decision:  d.decision,
+			Robots:    d.robots,
+		})
+	}
+	return traces, nil
+}
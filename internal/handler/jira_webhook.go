@@ -1,8 +1,14 @@
 package handler
 
 import (
+	"bytes"
+	"encoding/json"
+	"io"
 	"log"
 	"net/http"
+	"slices"
+	"time"
+	"whenchangesth/internal/metrics"
 	"whenchangesth/pkg"
 
 	"github.com/gin-gonic/gin"
@@ -10,159 +16,204 @@ import (
 
 // JiraWebhookHandler 处理 /jira/webhook 的 POST 请求
 func JiraWebhookHandler(c *gin.Context) {
-	// 读取请求Body
-	//body, err := c.GetRawData()
-	//if err != nil {
-	//	log.Printf("Failed to read request body: %v", err)
-	//	c.JSON(http.StatusBadRequest, gin.H{"error": "Invalid request body"})
-	//	return
-	//}
-	//
-	//// 打印原始Body（调试用）
-	//log.Printf("Raw request body: %s", string(body))
-	//
-	//// 注意：需要将body写回，因为c.GetRawData()会消费掉Reader
-	//c.Request.Body = io.NopCloser(bytes.NewBuffer(body))
-	//使用 Parse 方法解析请求体
-	result, err := pkg.Parse(c.Request, getAllEvents()...)
+	// 读取请求体，按事件类型计数后再解析
+	body, err := io.ReadAll(c.Request.Body)
+	if err != nil {
+		log.Printf("Failed to read request body: %v", err)
+		c.JSON(http.StatusBadRequest, gin.H{"error": "Invalid request body"})
+		return
+	}
+	metrics.WebhooksReceived.WithLabelValues(webhookLabels(body)).Inc()
+
+	result, err := ParseWebhook(body)
 	if err != nil {
 		log.Printf("Failed to parse webhook: %v", err)
+		metrics.WebhookParseFailures.WithLabelValues(metrics.ParseFailureReason(err)).Inc()
 		c.JSON(http.StatusBadRequest, gin.H{
 			"error": "Invalid webhook request",
 		})
 		return
 	}
-	//fmt.Println("result.(type)", reflect.TypeOf(result))
+
 	// 提取事件类型
-	var event pkg.Event
+	event, ok := webhookEvent(result)
+	if !ok {
+		log.Printf("Unhandled result type: %T", result)
+		c.JSON(http.StatusBadRequest, gin.H{
+			"error": "Unsupported hook type",
+		})
+		return
+	}
+
+	// 根据事件类型调用对应的处理器
+	if _, ok := eventHandlers[event]; !ok {
+		log.Printf("Unsupported event type: %s", event)
+		c.JSON(http.StatusBadRequest, gin.H{
+			"error": "Unsupported event type",
+		})
+		return
+	}
+
+	// 跳过 Jira 重复投递的 webhook
+	key := idempotencyKey(c.GetHeader(webhookIdentifierHeader), body)
+	if !markWebhookSeen(key, appCfg.Webhook.DedupTTL) {
+		log.Printf("Duplicate webhook ignored: %s", key)
+		c.JSON(http.StatusOK, gin.H{
+			"message": "Duplicate webhook ignored",
+		})
+		return
+	}
+
+	// 放入处理队列后立即返回，队列已满时返回 503 让 Jira 稍后重试
+	job := webhookJob{event: event, payload: result, received: time.Now()}
+	if appCfg.Archive.Enabled {
+		job.archive = newArchivedWebhook(c.Request.Header, body, job.received)
+	}
+	if !webhookPool.submit(job) {
+		log.Printf("⚠️ webhook 处理队列已满，拒绝事件 %s", event)
+		metrics.WebhookQueueRejected.Inc()
+		forgetWebhook(key)
+		c.JSON(http.StatusServiceUnavailable, gin.H{
+			"error": "Webhook queue is full",
+		})
+		return
+	}
+
+	c.JSON(http.StatusAccepted, gin.H{
+		"message": "Webhook accepted",
+	})
+}
+
+// webhookEvent 返回解析结果对应的事件类型
+func webhookEvent(result interface{}) (pkg.Event, bool) {
 	switch v := result.(type) {
 	case pkg.Event:
-		event = v
+		return v, true
 	case pkg.TransitionIssueStatusPayload:
-		event = pkg.StatusTransitionEvent
+		return pkg.StatusTransitionEvent, true
 	case pkg.IssueCreatedPayload:
-		event = pkg.IssueCreatedEvent
+		return pkg.IssueCreatedEvent, true
 	case pkg.IssueDeletedPayload:
-		event = pkg.IssueDeletedEvent
+		return pkg.IssueDeletedEvent, true
 	case pkg.IssueUpdatedPayload:
-		event = pkg.IssueUpdatedEvent
+		return pkg.IssueUpdatedEvent, true
 	case pkg.IssueGenericPayload:
-		event = pkg.IssueUpdatedEvent
+		return pkg.IssueUpdatedEvent, true
 	case pkg.IssueAssignedPayload:
-		event = pkg.IssueUpdatedEvent
+		return pkg.IssueUpdatedEvent, true
+	case pkg.IssueCommentCreatedPayload:
+		return pkg.IssueUpdatedEvent, true
+	case pkg.IssueCommentUpdatedPayload:
+		return pkg.IssueUpdatedEvent, true
+	case pkg.IssueCommentDeletedPayload:
+		return pkg.IssueUpdatedEvent, true
 	case pkg.IssueWorkLogCreatedPayload:
-		event = pkg.IssueWorkLogEvent
+		return pkg.IssueWorkLogEvent, true
 	case pkg.IssueWorkLogUpdatedPayload:
-		event = pkg.IssueWorkLogEvent
+		return pkg.IssueWorkLogEvent, true
 	case pkg.IssueWorkLogDeletedPayload:
-		event = pkg.IssueDeletedEvent
+		return pkg.IssueWorkLogEvent, true
 	case pkg.IssueMovedPayload:
-		event = pkg.IssueDeletedEvent
+		return pkg.IssueDeletedEvent, true
 	case pkg.IssueClosedPayload:
-		event = pkg.IssueDeletedEvent
+		return pkg.IssueDeletedEvent, true
 	case pkg.WorkLogCreatedPayload:
-		event = pkg.WorkLogCreatedEvent
+		return pkg.WorkLogCreatedEvent, true
 	case pkg.WorkLogUpdatedPayload:
-		event = pkg.WorkLogUpdatedEvent
+		return pkg.WorkLogUpdatedEvent, true
 	case pkg.WorkLogDeletedPayload:
-		event = pkg.WorkLogDeletedEvent
+		return pkg.WorkLogDeletedEvent, true
 	case pkg.CommentCreatedPayload:
-		event = pkg.CommentCreatedEvent
+		return pkg.CommentCreatedEvent, true
 	case pkg.CommentUpdatedPayload:
-		event = pkg.CommentUpdatedEvent
+		return pkg.CommentUpdatedEvent, true
 	case pkg.CommentDeletedPayload:
-		event = pkg.CommentDeletedEvent
+		return pkg.CommentDeletedEvent, true
 	case pkg.LinkCreatedPayload:
-		event = pkg.LinkCreatedEvent
+		return pkg.LinkCreatedEvent, true
 	case pkg.LinkDeletedPayload:
-		event = pkg.LinkDeletedEvent
+		return pkg.LinkDeletedEvent, true
 	case pkg.UserCreatedPayload:
-		event = pkg.UserCreatedEvent
+		return pkg.UserCreatedEvent, true
 	case pkg.UserUpdatedPayload:
-		event = pkg.UserUpdatedEvent
+		return pkg.UserUpdatedEvent, true
 	case pkg.UserDeletedPayload:
-		event = pkg.UserDeletedEvent
+		return pkg.UserDeletedEvent, true
 	case pkg.ProjectCreatedPayload:
-		event = pkg.ProjectCreatedEvent
+		return pkg.ProjectCreatedEvent, true
 	case pkg.ProjectUpdatedPayload:
-		event = pkg.ProjectUpdatedEvent
+		return pkg.ProjectUpdatedEvent, true
 	case pkg.ProjectDeletedPayload:
-		event = pkg.ProjectDeletedEvent
+		return pkg.ProjectDeletedEvent, true
 	case pkg.ProjectArchivedPayload:
-		event = pkg.ProjectArchivedEvent
+		return pkg.ProjectArchivedEvent, true
 	case pkg.ProjectRestoredPayload:
-		event = pkg.ProjectRestoredEvent
+		return pkg.ProjectRestoredEvent, true
 	case pkg.BoardCreatedPayload:
-		event = pkg.BoardCreatedEvent
+		return pkg.BoardCreatedEvent, true
 	case pkg.BoardUpdatedPayload:
-		event = pkg.BoardUpdatedEvent
+		return pkg.BoardUpdatedEvent, true
 	case pkg.BoardDeletedPayload:
-		event = pkg.BoardDeletedEvent
+		return pkg.BoardDeletedEvent, true
 	case pkg.BoardConfigurationChangedPayload:
-		event = pkg.BoardConfigurationChangedEvent
+		return pkg.BoardConfigurationChangedEvent, true
 	case pkg.SprintCreatedPayload:
-		event = pkg.SprintCreatedEvent
+		return pkg.SprintCreatedEvent, true
 	case pkg.SprintUpdatedPayload:
-		event = pkg.SprintUpdatedEvent
+		return pkg.SprintUpdatedEvent, true
 	case pkg.SprintDeletedPayload:
-		event = pkg.SprintDeletedEvent
+		return pkg.SprintDeletedEvent, true
 	case pkg.SprintStartedPayload:
-		event = pkg.SprintStartedEvent
+		return pkg.SprintStartedEvent, true
 	case pkg.SprintClosedPayload:
-		event = pkg.SprintClosedEvent
+		return pkg.SprintClosedEvent, true
 	case pkg.VersionCreatedPayload:
-		event = pkg.VersionCreatedEvent
+		return pkg.VersionCreatedEvent, true
 	case pkg.VersionUpdatedPayload:
-		event = pkg.VersionUpdatedEvent
+		return pkg.VersionUpdatedEvent, true
 	case pkg.VersionDeletedPayload:
-		event = pkg.VersionDeletedEvent
+		return pkg.VersionDeletedEvent, true
 	case pkg.VersionReleasedPayload:
-		event = pkg.VersionReleasedEvent
+		return pkg.VersionReleasedEvent, true
 	case pkg.VersionUnreleasedPayload:
-		event = pkg.VersionUnreleasedEvent
+		return pkg.VersionUnreleasedEvent, true
 	case pkg.OptionTimeTrackingChangedPayload:
-		event = pkg.OptionTimeTrackingChangedEvent
+		return pkg.OptionTimeTrackingChangedEvent, true
 	case pkg.OptionIssueLinksChangedPayload:
-		event = pkg.OptionIssueLinksChangedEvent
+		return pkg.OptionIssueLinksChangedEvent, true
 	case pkg.OptionSubTasksChangedPayload:
-		event = pkg.OptionSubTasksChangedEvent
+		return pkg.OptionSubTasksChangedEvent, true
 	case pkg.OptionAttachmentsChangedPayload:
-		event = pkg.OptionAttachmentsChangedEvent
+		return pkg.OptionAttachmentsChangedEvent, true
 	case pkg.OptionWatchingChangedPayload:
-		event = pkg.OptionWatchingChangedEvent
+		return pkg.OptionWatchingChangedEvent, true
 	case pkg.OptionVotingChangedPayload:
-		event = pkg.OptionVotingChangedEvent
+		return pkg.OptionVotingChangedEvent, true
 	case pkg.OptionUnassignedIssuesChangedPayload:
-		event = pkg.OptionUnassignedIssuesChangedEvent
+		return pkg.OptionUnassignedIssuesChangedEvent, true
 	default:
-		log.Printf("Unhandled result type: %T", result)
-		c.JSON(http.StatusBadRequest, gin.H{
-			"error": "Unsupported hook type",
-		})
-		return
+		return "", false
 	}
+}
 
-	// 根据事件类型调用对应的处理器
-	handlerFunc, ok := eventHandlers[event]
-	if !ok {
-		log.Printf("Unsupported event type: %s", event)
-		c.JSON(http.StatusBadRequest, gin.H{
-			"error": "Unsupported event type",
-		})
-		return
+// webhookLabels 返回请求体中的 webhookEvent 和 issue_event_type_name，未知事件统一为 unknown，避免标签无限增长
+func webhookLabels(body []byte) (event, action string) {
+	var hook struct {
+		Event  string `json:"webhookEvent"`
+		Action string `json:"issue_event_type_name"`
 	}
-
-	// 调用处理器并处理结果
-	if err := handlerFunc(result); err != nil {
-		log.Printf("Error processing event %s: %v", event, err)
-		c.JSON(http.StatusInternalServerError, gin.H{
-			"error": "Failed to process event",
-		})
-		return
+	_ = json.Unmarshal(body, &hook)
+	if !slices.Contains(getAllEvents(), pkg.Event(hook.Event)) {
+		return "unknown", ""
 	}
+	return hook.Event, hook.Action
+}
 
-	// 返回成功响应
-	c.JSON(http.StatusOK, gin.H{
-		"message": "Webhook received and processed successfully",
-	})
+// ParseWebhook 解析一份保存下来的 webhook 请求体，结果与在线接收时一致
+func ParseWebhook(body []byte) (interface{}, error) {
+	req, err := http.NewRequest(http.MethodPost, "/jira/webhook", bytes.NewReader(body))
+	if err != nil {
+		return nil, err
+	}
+	return pkg.Parse(req, getAllEvents()...)
 }
@@ -0,0 +1,48 @@
+package handler
+
+import (
+	"net/url"
+	"strings"
+	"whenchangesth/internal/conf"
+)
+
+// issueLink 返回问题的链接，self 为 payload 中的 issue.self，可以为空
+func issueLink(key, self string) string {
+	return buildIssueLink(appCfg.Jira, key, self)
+}
+
+// buildIssueLink 根据配置生成问题链接
+func buildIssueLink(cfg conf.JiraConfig, key, self string) string {
+	if key == "" {
+		return ""
+	}
+
+	base := strings.TrimRight(cfg.BaseURL, "/") + strings.TrimRight(cfg.ContextPath, "/")
+	if cfg.FromPayload {
+		if derived := baseFromSelf(self); derived != "" {
+			base = derived
+		}
+	}
+	if base == "" {
+		return ""
+	}
+
+	link := base + "/browse/" + url.PathEscape(key)
+	if cfg.LinkStyle == conf.LinkStyleDingTalkSidebar {
+		return "dingtalk://dingtalkclient/page/link?url=" + url.QueryEscape(link) + "&pc_slide=true"
+	}
+	return link
+}
+
+// baseFromSelf 从 REST 地址（如 https://jira.example.com/jira/rest/api/2/issue/10001）中取出站点地址，保留上下文路径
+func baseFromSelf(self string) string {
+	idx := strings.Index(self, "/rest/")
+	if idx <= 0 {
+		return ""
+	}
+	u, err := url.Parse(self[:idx])
+	if err != nil || u.Scheme == "" || u.Host == "" {
+		return ""
+	}
+	return strings.TrimRight(u.String(), "/")
+}
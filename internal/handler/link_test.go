@@ -0,0 +1,28 @@
+package handler
+
+import (
+	"testing"
+	"whenchangesth/internal/conf"
+)
+
+func TestBuildIssueLink(t *testing.T) {
+	self := "https://jira.example.com/jira/rest/api/2/issue/10001"
+	cases := []struct {
+		name string
+		cfg  conf.JiraConfig
+		self string
+		want string
+	}{
+		{"base url", conf.JiraConfig{BaseURL: "https://example.atlassian.net/"}, self, "https://example.atlassian.net/browse/ABC-1"},
+		{"context path", conf.JiraConfig{BaseURL: "https://jira.local", ContextPath: "/jira"}, "", "https://jira.local/jira/browse/ABC-1"},
+		{"from payload", conf.JiraConfig{BaseURL: "https://fallback", FromPayload: true}, self, "https://jira.example.com/jira/browse/ABC-1"},
+		{"from payload fallback", conf.JiraConfig{BaseURL: "https://fallback", FromPayload: true}, "", "https://fallback/browse/ABC-1"},
+		{"sidebar", conf.JiraConfig{BaseURL: "https://jira.local", LinkStyle: conf.LinkStyleDingTalkSidebar}, "",
+			"dingtalk://dingtalkclient/page/link?url=https%3A%2F%2Fjira.local%2Fbrowse%2FABC-1&pc_slide=true"},
+	}
+	for _, c := range cases {
+		if got := buildIssueLink(c.cfg, "ABC-1", c.self); got != c.want {
+			t.Errorf("%s: got %q, want %q", c.name, got, c.want)
+		}
+	}
+}
@@ -0,0 +1,119 @@
+package handler
+
+import (
+	"context"
+	"time"
+	"whenchangesth/internal/metrics"
+
+	"github.com/go-redis/redis/v8"
+	"github.com/prometheus/client_golang/prometheus"
+)
+
+// redisMetricsHook 按命令统计 Redis 错误，key 不存在不计为错误
+type redisMetricsHook struct{}
+
+func (redisMetricsHook) BeforeProcess(ctx context.Context, _ redis.Cmder) (context.Context, error) {
+	return ctx, nil
+}
+
+func (redisMetricsHook) AfterProcess(_ context.Context, cmd redis.Cmder) error {
+	if err := cmd.Err(); err != nil && err != redis.Nil {
+		metrics.RedisErrors.WithLabelValues(cmd.Name()).Inc()
+	}
+	return nil
+}
+
+func (redisMetricsHook) BeforeProcessPipeline(ctx context.Context, _ []redis.Cmder) (context.Context, error) {
+	return ctx, nil
+}
+
+func (h redisMetricsHook) AfterProcessPipeline(ctx context.Context, cmds []redis.Cmder) error {
+	for _, cmd := range cmds {
+		_ = h.AfterProcess(ctx, cmd)
+	}
+	return nil
+}
+
+// stateCollector 在抓取时读取去抖桶、重试队列和发送队列的当前状态
+type stateCollector struct {
+	webhookQueue   *prometheus.Desc
+	webhookCap     *prometheus.Desc
+	pendingBuckets *prometheus.Desc
+	overdue        *prometheus.Desc
+	retryQueue     *prometheus.Desc
+	queueDepth     *prometheus.Desc
+	queueOldest    *prometheus.Desc
+	merged         *prometheus.Desc
+	throttled      *prometheus.Desc
+}
+
+func newStateCollector() *stateCollector {
+	return &stateCollector{
+		webhookQueue: prometheus.NewDesc("jirahook_webhook_queue_depth",
+			"Parsed webhooks waiting for a worker.", nil, nil),
+		webhookCap: prometheus.NewDesc("jirahook_webhook_queue_capacity",
+			"Capacity of the webhook processing queue.", nil, nil),
+		pendingBuckets: prometheus.NewDesc("jirahook_debounce_buckets_pending",
+			"Debounce buckets waiting to be flushed.", nil, nil),
+		overdue: prometheus.NewDesc("jirahook_debounce_overdue_seconds",
+			"How long the oldest due debounce bucket has been waiting past its deadline.", nil, nil),
+		retryQueue: prometheus.NewDesc("jirahook_dingtalk_retry_queue",
+			"Failed DingTalk messages waiting to be retried.", nil, nil),
+		queueDepth: prometheus.NewDesc("jirahook_dingtalk_queue_depth",
+			"Messages waiting in the per-robot rate limit queue.", []string{"robot"}, nil),
+		queueOldest: prometheus.NewDesc("jirahook_dingtalk_queue_oldest_wait_seconds",
+			"How long the oldest message in the per-robot rate limit queue has waited.", []string{"robot"}, nil),
+		merged: prometheus.NewDesc("jirahook_dingtalk_merged_total",
+			"Messages merged into another post by the rate limiter.", []string{"robot"}, nil),
+		throttled: prometheus.NewDesc("jirahook_dingtalk_throttled_total",
+			"Times a robot's send budget was exhausted.", []string{"robot"}, nil),
+	}
+}
+
+func (s *stateCollector) Describe(ch chan<- *prometheus.Desc) {
+	ch <- s.webhookQueue
+	ch <- s.webhookCap
+	ch <- s.pendingBuckets
+	ch <- s.overdue
+	ch <- s.retryQueue
+	ch <- s.queueDepth
+	ch <- s.queueOldest
+	ch <- s.merged
+	ch <- s.throttled
+}
+
+func (s *stateCollector) Collect(ch chan<- prometheus.Metric) {
+	if webhookPool != nil {
+		queued, capacity := webhookPool.depth()
+		ch <- prometheus.MustNewConstMetric(s.webhookQueue, prometheus.GaugeValue, float64(queued))
+		ch <- prometheus.MustNewConstMetric(s.webhookCap, prometheus.GaugeValue, float64(capacity))
+	}
+
+	c, cancel := context.WithTimeout(ctx, 2*time.Second)
+	defer cancel()
+
+	// Redis 不可用时跳过这些指标，错误已由 redis_errors_total 统计
+	if n, err := RedisClient.ZCard(c, scheduleKey).Result(); err == nil {
+		ch <- prometheus.MustNewConstMetric(s.pendingBuckets, prometheus.GaugeValue, float64(n))
+	}
+	if oldest, err := RedisClient.ZRangeWithScores(c, scheduleKey, 0, 0).Result(); err == nil {
+		overdue := 0.0
+		if len(oldest) > 0 {
+			deadline := time.UnixMilli(int64(oldest[0].Score))
+			if d := time.Since(deadline); d > 0 {
+				overdue = d.Seconds()
+			}
+		}
+		ch <- prometheus.MustNewConstMetric(s.overdue, prometheus.GaugeValue, overdue)
+	}
+	if n, err := RedisClient.ZCard(c, retryQueueKey).Result(); err == nil {
+		ch <- prometheus.MustNewConstMetric(s.retryQueue, prometheus.GaugeValue, float64(n))
+	}
+
+	for robot, st := range sendQueueStats() {
+		ch <- prometheus.MustNewConstMetric(s.queueDepth, prometheus.GaugeValue, float64(st.Queued), robot)
+		ch <- prometheus.MustNewConstMetric(s.queueOldest, prometheus.GaugeValue, st.OldestWaitSeconds, robot)
+		ch <- prometheus.MustNewConstMetric(s.merged, prometheus.CounterValue, float64(st.Merged), robot)
+		ch <- prometheus.MustNewConstMetric(s.throttled, prometheus.CounterValue, float64(st.Throttled), robot)
+	}
+}
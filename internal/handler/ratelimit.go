@@ -0,0 +1,259 @@
+package handler
+
+import (
+	"context"
+	"fmt"
+	"strings"
+	"sync"
+	"time"
+	"whenchangesth/internal/ding"
+	"whenchangesth/internal/metrics"
+
+	"github.com/go-redis/redis/v8"
+)
+
+// 钉钉按机器人限流，令牌桶保存在 Redis 中，多个实例共享同一份额度
+// 额度用尽时消息在本实例排队，取得下一个令牌后将同一机器人排队的消息合并为一条发送
+const (
+	rateKeyPrefix  = "dingtalk_rate:"
+	mergeSeparator = "\n\n---\n\n"
+)
+
+// rateLimitScript 按经过的时间补充令牌并尝试取走一个，返回还需等待的毫秒数，0 表示已取得
+var rateLimitScript = redis.NewScript(`
+local rate = tonumber(ARGV[1])
+local burst = tonumber(ARGV[2])
+local now = tonumber(ARGV[3])
+local state = redis.call('HMGET', KEYS[1], 'tokens', 'ts')
+local tokens = tonumber(state[1]) or burst
+local ts = tonumber(state[2]) or now
+if now > ts then
+    tokens = math.min(burst, tokens + (now - ts) * rate)
+    ts = now
+end
+local wait = 0
+if tokens >= 1 then
+    tokens = tokens - 1
+else
+    wait = math.ceil((1 - tokens) / rate)
+end
+redis.call('HSET', KEYS[1], 'tokens', tostring(tokens), 'ts', ts)
+redis.call('PEXPIRE', KEYS[1], math.ceil(burst / rate) + 1000)
+return wait`)
+
+// takeSendToken 从机器人的令牌桶中取一个令牌，额度不足时返回需要等待的时间
+func takeSendToken(robot string) (time.Duration, error) {
+	cfg := appCfg.RateLimit
+	rate := float64(cfg.PerMinute) / float64(time.Minute/time.Millisecond)
+	wait, err := rateLimitScript.Run(ctx, RedisClient, []string{rateKeyPrefix + robot},
+		rate, cfg.Burst, time.Now().UnixMilli()).Int64()
+	if err != nil {
+		return 0, err
+	}
+	return time.Duration(wait) * time.Millisecond, nil
+}
+
+// outgoing 是一条等待发送的消息，发送完成后以发送结果调用 done
+type outgoing struct {
+	content  string
+	mentions []mention
+	queuedAt time.Time
+	done     func(error)
+}
+
+// SendQueueStats 记录单个机器人的限流和排队情况
+type SendQueueStats struct {
+	Queued            int     `json:"queued"`
+	OldestWaitSeconds float64 `json:"oldest_wait_seconds"`
+	Sent              uint64  `json:"sent"`
+	Throttled         uint64  `json:"throttled"`
+	Merged            uint64  `json:"merged"`
+	LastDelaySeconds  float64 `json:"last_delay_seconds"`
+	MaxDelaySeconds   float64 `json:"max_delay_seconds"`
+}
+
+// robotQueue 是单个机器人的发送队列，同一时间只有一个协程在等待令牌并发送
+type robotQueue struct {
+	robot string
+	take  func(robot string) (time.Duration, error)
+	send  func(robot, content string, mentions []mention) error
+	// ctx 结束后不再等待额度
+	ctx context.Context
+
+	mu       sync.Mutex
+	pending  []outgoing
+	draining bool
+	stats    SendQueueStats
+}
+
+// sendQueues 按机器人名称保存发送队列
+var (
+	sendQueues   = make(map[string]*robotQueue)
+	sendQueuesMu sync.Mutex
+)
+
+// sendQueueFor 返回机器人的发送队列，不存在时创建
+func sendQueueFor(robot string) *robotQueue {
+	sendQueuesMu.Lock()
+	defer sendQueuesMu.Unlock()
+
+	q, ok := sendQueues[robot]
+	if !ok {
+		q = &robotQueue{robot: robot, take: takeSendToken, send: sendDingTalkNotification, ctx: runCtx}
+		sendQueues[robot] = q
+	}
+	return q
+}
+
+// submitNotification 经过限流发送钉钉通知，发送完成后以结果调用 done
+func submitNotification(robot, content string, mentions []mention, done func(error)) {
+	sendQueueFor(robot).submit(outgoing{content: content, mentions: mentions, queuedAt: time.Now(), done: done})
+}
+
+// submit 将消息放入队列，队列空闲时立即开始发送
+func (q *robotQueue) submit(o outgoing) {
+	q.mu.Lock()
+	q.pending = append(q.pending, o)
+	start := !q.draining
+	q.draining = true
+	q.mu.Unlock()
+
+	if start {
+		goBackground(q.drain)
+	}
+}
+
+// drain 逐个取得令牌发送，每次将队首不超过长度上限的消息合并为一条
+func (q *robotQueue) drain() {
+	for {
+		q.mu.Lock()
+		if len(q.pending) == 0 {
+			q.draining = false
+			q.mu.Unlock()
+			return
+		}
+		q.mu.Unlock()
+
+		wait, err := q.take(q.robot)
+		if err != nil {
+			// 令牌桶不可用时不阻塞发送，由钉钉自身的限流兜底
+			fmt.Printf("⚠️ 读取机器人 %s 的发送额度失败: %v\n", q.robot, err)
+			wait = 0
+		}
+		if wait > 0 {
+			// 退出时不再等待额度，剩余消息转入重试队列
+			if q.ctx.Err() != nil {
+				q.abandon()
+				return
+			}
+			q.mu.Lock()
+			q.stats.Throttled++
+			q.mu.Unlock()
+			select {
+			case <-time.After(wait):
+			case <-q.ctx.Done():
+			}
+			continue
+		}
+
+		q.mu.Lock()
+		batch := q.pending[:mergeable(q.pending, ding.MaxTextBytes)]
+		q.pending = q.pending[len(batch):]
+		delay := time.Since(batch[0].queuedAt)
+		q.stats.Sent++
+		q.stats.Merged += uint64(len(batch) - 1)
+		q.stats.LastDelaySeconds = delay.Seconds()
+		if delay.Seconds() > q.stats.MaxDelaySeconds {
+			q.stats.MaxDelaySeconds = delay.Seconds()
+		}
+		q.mu.Unlock()
+		for _, o := range batch {
+			metrics.DingTalkQueueDelay.WithLabelValues(q.robot).Observe(time.Since(o.queuedAt).Seconds())
+		}
+
+		content, mentions := mergeOutgoing(batch)
+		err = q.send(q.robot, content, mentions)
+		for _, o := range batch {
+			if o.done != nil {
+				o.done(err)
+			}
+		}
+	}
+}
+
+// abandon 清空队列，以 errShuttingDown 通知每条消息
+func (q *robotQueue) abandon() {
+	q.mu.Lock()
+	pending := q.pending
+	q.pending = nil
+	q.draining = false
+	q.mu.Unlock()
+
+	for _, o := range pending {
+		if o.done != nil {
+			o.done(errShuttingDown)
+		}
+	}
+}
+
+// mergeable 返回队首可以合并为一条且不超过 maxBytes 的消息数，至少为 1
+func mergeable(pending []outgoing, maxBytes int) int {
+	size := len(pending[0].content)
+	n := 1
+	for ; n < len(pending); n++ {
+		size += len(mergeSeparator) + len(pending[n].content)
+		if size > maxBytes {
+			break
+		}
+	}
+	return n
+}
+
+// mergeOutgoing 将多条消息合并为一条，@ 的人取并集
+func mergeOutgoing(batch []outgoing) (string, []mention) {
+	if len(batch) == 1 {
+		return batch[0].content, batch[0].mentions
+	}
+	parts := make([]string, 0, len(batch))
+	seen := make(map[string]bool)
+	var mentions []mention
+	for _, o := range batch {
+		parts = append(parts, o.content)
+		for _, m := range o.mentions {
+			if t := m.token(); t != "" && !seen[t] {
+				seen[t] = true
+				mentions = append(mentions, m)
+			}
+		}
+	}
+	return strings.Join(parts, mergeSeparator), mentions
+}
+
+// Stats 返回队列当前的统计信息
+func (q *robotQueue) Stats() SendQueueStats {
+	q.mu.Lock()
+	defer q.mu.Unlock()
+
+	s := q.stats
+	s.Queued = len(q.pending)
+	if s.Queued > 0 {
+		s.OldestWaitSeconds = time.Since(q.pending[0].queuedAt).Seconds()
+	}
+	return s
+}
+
+// sendQueueStats 返回各机器人发送队列的统计信息
+func sendQueueStats() map[string]SendQueueStats {
+	sendQueuesMu.Lock()
+	queues := make([]*robotQueue, 0, len(sendQueues))
+	for _, q := range sendQueues {
+		queues = append(queues, q)
+	}
+	sendQueuesMu.Unlock()
+
+	stats := make(map[string]SendQueueStats, len(queues))
+	for _, q := range queues {
+		stats[q.robot] = q.Stats()
+	}
+	return stats
+}
@@ -0,0 +1,161 @@
+package handler
+
+import (
+	"context"
+	"errors"
+	"reflect"
+	"sync"
+	"testing"
+	"time"
+)
+
+func TestRobotQueueMergesWhileThrottled(t *testing.T) {
+	var (
+		mu    sync.Mutex
+		sent  []string
+		calls int
+	)
+	firstSending := make(chan struct{})
+	releaseFirst := make(chan struct{})
+	q := &robotQueue{
+		robot: "team",
+		ctx:   context.Background(),
+		take: func(string) (time.Duration, error) {
+			mu.Lock()
+			defer mu.Unlock()
+			calls++
+			// 第二次取令牌时额度不足，需要等待
+			if calls == 2 {
+				return 10 * time.Millisecond, nil
+			}
+			return 0, nil
+		},
+		send: func(robot, content string, mentions []mention) error {
+			mu.Lock()
+			first := len(sent) == 0
+			sent = append(sent, content)
+			mu.Unlock()
+			if first {
+				close(firstSending)
+				<-releaseFirst
+			}
+			return nil
+		},
+	}
+
+	var done sync.WaitGroup
+	submit := func(content string) {
+		done.Add(1)
+		q.submit(outgoing{content: content, queuedAt: time.Now(), done: func(error) { done.Done() }})
+	}
+	submit("a")
+	<-firstSending
+	submit("b")
+	submit("c")
+	if s := q.Stats(); s.Queued != 2 {
+		t.Errorf("queued = %d, want 2", s.Queued)
+	}
+	close(releaseFirst)
+	done.Wait()
+
+	mu.Lock()
+	defer mu.Unlock()
+	want := []string{"a", "b" + mergeSeparator + "c"}
+	if !reflect.DeepEqual(sent, want) {
+		t.Errorf("sent = %q, want %q", sent, want)
+	}
+	s := q.Stats()
+	if s.Sent != 2 || s.Merged != 1 || s.Throttled != 1 || s.Queued != 0 {
+		t.Errorf("stats = %+v", s)
+	}
+}
+
+func TestMergeOutgoingMentions(t *testing.T) {
+	_, mentions := mergeOutgoing([]outgoing{
+		{content: "a", mentions: []mention{{UserID: "u1"}, {Mobile: "138"}}},
+		{content: "b", mentions: []mention{{UserID: "u1"}, {UserID: "u2"}}},
+	})
+	want := []mention{{UserID: "u1"}, {Mobile: "138"}, {UserID: "u2"}}
+	if !reflect.DeepEqual(mentions, want) {
+		t.Errorf("mentions = %+v, want %+v", mentions, want)
+	}
+}
+
+func TestMergeableRespectsLimit(t *testing.T) {
+	pending := []outgoing{{content: "aaaa"}, {content: "bbbb"}, {content: "cccc"}}
+	limit := 8 + len(mergeSeparator)
+	if n := mergeable(pending, limit); n != 2 {
+		t.Errorf("mergeable = %d, want 2", n)
+	}
+	if n := mergeable(pending, 1); n != 1 {
+		t.Errorf("mergeable with tiny limit = %d, want 1", n)
+	}
+}
+
+func TestRobotQueueAbandonsOnShutdown(t *testing.T) {
+	c, cancel := context.WithCancel(context.Background())
+	q := &robotQueue{
+		robot: "team",
+		ctx:   c,
+		take:  func(string) (time.Duration, error) { return time.Hour, nil },
+		send: func(string, string, []mention) error {
+			t.Error("message sent without budget")
+			return nil
+		},
+	}
+
+	result := make(chan error, 2)
+	q.submit(outgoing{content: "a", queuedAt: time.Now(), done: func(err error) { result <- err }})
+	q.submit(outgoing{content: "b", queuedAt: time.Now(), done: func(err error) { result <- err }})
+	cancel()
+	for i := 0; i < 2; i++ {
+		select {
+		case err := <-result:
+			if !errors.Is(err, errShuttingDown) {
+				t.Errorf("done(%v), want errShuttingDown", err)
+			}
+		case <-time.After(time.Second):
+			t.Fatal("queue kept waiting after shutdown")
+		}
+	}
+	if s := q.Stats(); s.Queued != 0 {
+		t.Errorf("queued = %d after shutdown", s.Queued)
+	}
+}
+
+func TestDeliverNotificationThenWaitsForSend(t *testing.T) {
+	release := make(chan struct{})
+	sendQueuesMu.Lock()
+	sendQueues["durable"] = &robotQueue{
+		robot: "durable",
+		ctx:   context.Background(),
+		take:  func(string) (time.Duration, error) { return 0, nil },
+		send: func(robot, content string, mentions []mention) error {
+			<-release
+			return nil
+		},
+	}
+	sendQueuesMu.Unlock()
+	defer func() {
+		sendQueuesMu.Lock()
+		delete(sendQueues, "durable")
+		sendQueuesMu.Unlock()
+	}()
+
+	saved := make(chan bool, 1)
+	deliverNotificationThen("durable", "digest", nil, func(ok bool) { saved <- ok })
+	select {
+	case <-saved:
+		t.Fatal("done called before the message was sent")
+	case <-time.After(20 * time.Millisecond):
+	}
+	close(release)
+	select {
+	case ok := <-saved:
+		if !ok {
+			t.Error("done(false) after a successful send")
+		}
+	case <-time.After(time.Second):
+		t.Fatal("done not called after send")
+	}
+}
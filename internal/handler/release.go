@@ -0,0 +1,151 @@
+package handler
+
+import (
+	"database/sql"
+	"errors"
+	"fmt"
+	"log"
+	"net/http"
+	"slices"
+	"whenchangesth/internal/objects"
+	"whenchangesth/internal/report"
+	"whenchangesth/pkg"
+
+	"github.com/gin-gonic/gin"
+)
+
+// errVersionNotFound 表示没有记录到该版本
+var errVersionNotFound = errors.New("version not found")
+
+// handleVersion 处理版本发布和撤销发布事件
+// 发布时根据记录的问题快照生成发布说明，发送到版本所属项目的机器人
+func handleVersion(payload interface{}) error {
+	var v *objects.Version
+	var released bool
+	switch pl := payload.(type) {
+	case pkg.VersionReleasedPayload:
+		v, released = pl.Version, true
+	case pkg.VersionUnreleasedPayload:
+		v = pl.Version
+	default:
+		return fmt.Errorf("invalid payload type for version: %T", payload)
+	}
+	if v == nil || v.ID == "" {
+		return nil
+	}
+	v.Released = released
+
+	if err := writeDB("jirahook_version", func(db *sql.DB) error {
+		return saveVersion(db, v)
+	}); err != nil {
+		return err
+	}
+
+	// 演练时没有写入版本，使用 payload 中的版本信息
+	var release report.Release
+	var projects []string
+	var err error
+	if dryRun != nil {
+		release = report.Release{Name: v.Name, Description: v.Description, ReleaseDate: v.ReleaseDate}
+		projects, err = loadReleaseIssues(v.ID, &release)
+	} else {
+		release, projects, err = loadRelease(v.ID)
+	}
+	if err != nil {
+		return err
+	}
+	content := release.Markdown()
+	if !released {
+		content = fmt.Sprintf("### **版本撤销发布: %s**\n- 共 %d 个问题的修复版本为该版本\n", release.Name, len(release.Issues))
+	}
+	for _, robot := range projectRobots(projects) {
+		deliverNotification(robot, content, nil)
+	}
+	return nil
+}
+
+// projectRobots 按路由表返回这些项目的机器人
+func projectRobots(projects []string) []string {
+	if len(projects) == 0 {
+		return routeRobots(appCfg.Routes, nil)
+	}
+	var robots []string
+	for _, key := range projects {
+		issue := &objects.Issue{Fields: &objects.IssueFields{Project: &objects.Project{Key: key}}}
+		for _, robot := range routeRobots(appCfg.Routes, issue) {
+			if !slices.Contains(robots, robot) {
+				robots = append(robots, robot)
+			}
+		}
+	}
+	return robots
+}
+
+// loadRelease 读取版本信息和修复版本为该版本的问题，同时返回问题所属的项目
+func loadRelease(versionID string) (report.Release, []string, error) {
+	var r report.Release
+	err := withDB(func(db *sql.DB) error {
+		err := db.QueryRow(`SELECT name, description, release_date FROM jirahook_version WHERE version_id = ?`, versionID).
+			Scan(&r.Name, &r.Description, &r.ReleaseDate)
+		if errors.Is(err, sql.ErrNoRows) {
+			return errVersionNotFound
+		}
+		return err
+	})
+	if errors.Is(err, errVersionNotFound) {
+		return r, nil, err
+	}
+	if err != nil {
+		return r, nil, fmt.Errorf("读取版本 %s 失败: %v", versionID, err)
+	}
+	projects, err := loadReleaseIssues(versionID, &r)
+	return r, projects, err
+}
+
+// loadReleaseIssues 读取修复版本为该版本的问题并加入 r，返回问题所属的项目
+func loadReleaseIssues(versionID string, r *report.Release) ([]string, error) {
+	var projects []string
+	err := withDB(func(db *sql.DB) error {
+		rows, err := db.Query(`
+            SELECT i.issue_key, i.summary, i.self, i.issue_type, i.status, i.done, v.project_key
+            FROM jirahook_issue_version v JOIN jirahook_issue i ON i.issue_id = v.issue_id
+            WHERE v.version_id = ? AND i.deleted = 0`, versionID)
+		if err != nil {
+			return err
+		}
+		defer rows.Close()
+
+		for rows.Next() {
+			var i report.ReleaseIssue
+			var self, project string
+			if err := rows.Scan(&i.Key, &i.Summary, &self, &i.Type, &i.Status, &i.Done, &project); err != nil {
+				return err
+			}
+			i.Link = issueLink(i.Key, self)
+			r.Issues = append(r.Issues, i)
+			if project != "" && !slices.Contains(projects, project) {
+				projects = append(projects, project)
+			}
+		}
+		return rows.Err()
+	})
+	if err != nil {
+		return nil, fmt.Errorf("读取版本 %s 的问题失败: %v", versionID, err)
+	}
+	return projects, nil
+}
+
+// ReleaseNotesHandler 以 markdown 返回某个版本的发布说明，便于粘贴到 changelog
+func ReleaseNotesHandler(c *gin.Context) {
+	release, _, err := loadRelease(c.Param("id"))
+	if errors.Is(err, errVersionNotFound) {
+		c.JSON(http.StatusNotFound, gin.H{"error": "Version not found"})
+		return
+	}
+	if err != nil {
+		log.Printf("Failed to load release notes: %v", err)
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to load release notes"})
+		return
+	}
+	c.Data(http.StatusOK, "text/markdown; charset=utf-8", []byte(release.Markdown()))
+}
@@ -0,0 +1,159 @@
+package handler
+
+import (
+	"context"
+	"fmt"
+	"io"
+	"slices"
+	"strings"
+	"time"
+	"whenchangesth/internal/conf"
+	"whenchangesth/internal/store"
+)
+
+// dryRun 不为 nil 时处于演练模式：不写入 Redis 和 MySQL，也不发送通知，只输出渲染后的消息
+var dryRun *dryRunRecorder
+
+// dryRunRecorder 在内存中汇总去抖桶，并输出本应发送的消息
+type dryRunRecorder struct {
+	out     io.Writer
+	buckets []*dryRunBucket
+	index   map[string]*dryRunBucket
+}
+
+// dryRunBucket 是演练时某个机器人下某类事件、某个操作人的去抖桶
+type dryRunBucket struct {
+	robot, eventType, operator string
+	events                     []map[string]string
+	tokens                     []string
+}
+
+func newDryRunRecorder(out io.Writer) *dryRunRecorder {
+	return &dryRunRecorder{out: out, index: make(map[string]*dryRunBucket)}
+}
+
+// push 将事件放入各机器人的去抖桶，与写入 Redis 时的分桶方式一致
+func (r *dryRunRecorder) push(robots []string, eventType, operator string, event map[string]string, tokens []interface{}) {
+	for _, robot := range robots {
+		key, _ := bucketKeys(robot, eventType, operator)
+		b, ok := r.index[key]
+		if !ok {
+			b = &dryRunBucket{robot: robot, eventType: eventType, operator: operator}
+			r.index[key] = b
+			r.buckets = append(r.buckets, b)
+		}
+		b.events = append(b.events, event)
+		for _, t := range tokens {
+			if s := t.(string); !slices.Contains(b.tokens, s) {
+				b.tokens = append(b.tokens, s)
+			}
+		}
+	}
+}
+
+// print 输出一条本应发送的消息
+func (r *dryRunRecorder) print(robot, content string, mentions []mention) {
+	var at []string
+	for _, m := range mentions {
+		if m.UserID != "" {
+			at = append(at, m.UserID)
+		} else if m.Mobile != "" {
+			at = append(at, m.Mobile)
+		}
+	}
+	header := "--- 机器人 " + robot
+	if len(at) > 0 {
+		header += "，@ " + strings.Join(at, ", ")
+	}
+	fmt.Fprintf(r.out, "%s\n%s\n\n", header, content)
+}
+
+// flush 渲染并输出全部去抖桶，只在最后一条 @ 相关人员
+func (r *dryRunRecorder) flush() {
+	for _, b := range r.buckets {
+		parts, mentions, err := renderBucket(b.robot, b.eventType, b.operator, b.events, b.tokens)
+		if err != nil {
+			fmt.Fprintf(r.out, "--- 机器人 %s 的 %s 消息渲染失败: %v\n\n", b.robot, b.eventType, err)
+			continue
+		}
+		for i, content := range parts {
+			if content == "" {
+				continue
+			}
+			if i == len(parts)-1 {
+				r.print(b.robot, content, mentions)
+			} else {
+				r.print(b.robot, content, nil)
+			}
+		}
+	}
+	r.buckets, r.index = nil, make(map[string]*dryRunBucket)
+}
+
+// ReplayOptions 是重放 webhook 存档的选项
+type ReplayOptions struct {
+	Filter ArchiveFilter
+	// DryRun 时只读取存档，将渲染后的消息输出到 Out，不写入也不发送
+	DryRun bool
+	Out    io.Writer
+}
+
+// Replay 按收到的顺序将存档的 webhook 交给事件处理器，返回重放和跳过的条数
+// 非演练时与服务使用相同的 Redis 和 MySQL，但不启动 worker 和轮询，去抖桶由正在运行的服务按时发送，
+// 返回前等待立即发送的消息发送完成
+func Replay(cfg *conf.Config, opts ReplayOptions) (replayed, skipped int, err error) {
+	if opts.DryRun {
+		if err := loadMessaging(cfg); err != nil {
+			return 0, 0, err
+		}
+		db, err := store.Open(ctx, cfg.MySQL)
+		if err != nil {
+			return 0, 0, fmt.Errorf("MySQL 连接失败: %v", err)
+		}
+		mysqlDB = db
+		dryRun = newDryRunRecorder(opts.Out)
+		defer func() {
+			dryRun = nil
+			mysqlDB.Close()
+		}()
+	} else {
+		if err := loadMessaging(cfg); err != nil {
+			return 0, 0, err
+		}
+		if err := initStorage(cfg); err != nil {
+			return 0, 0, err
+		}
+		defer func() {
+			c, cancel := context.WithTimeout(context.Background(), cfg.Server.ShutdownTimeout)
+			defer cancel()
+			if shutdownErr := Shutdown(c); shutdownErr != nil && err == nil {
+				err = shutdownErr
+			}
+		}()
+	}
+
+	err = eachArchived(opts.Filter, func(a *archivedWebhook) error {
+		result, err := ParseWebhook(a.Body)
+		if err != nil {
+			fmt.Fprintf(opts.Out, "跳过 #%d: 解析失败: %v\n", a.ID, err)
+			skipped++
+			return nil
+		}
+		event, ok := webhookEvent(result)
+		if _, supported := eventHandlers[event]; !ok || !supported {
+			fmt.Fprintf(opts.Out, "跳过 #%d: 不支持的事件 %s\n", a.ID, a.Event)
+			skipped++
+			return nil
+		}
+
+		fmt.Fprintf(opts.Out, "重放 #%d %s %s %s %s\n",
+			a.ID, a.ReceivedAt.Format(time.DateTime), a.Event, a.Action, a.IssueKey)
+		processWebhook(webhookJob{event: event, payload: result, received: time.Now()})
+		replayed++
+		return nil
+	})
+	if dryRun != nil {
+		dryRun.flush()
+	}
+	return replayed, skipped, err
+}
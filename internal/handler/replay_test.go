@@ -0,0 +1,56 @@
+package handler
+
+import (
+	"bytes"
+	"strings"
+	"testing"
+	"whenchangesth/internal/render"
+)
+
+func TestDryRunRendersBucketsWithoutStorage(t *testing.T) {
+	tmpls, err := render.Load("", []string{"default", "qa"})
+	if err != nil {
+		t.Fatal(err)
+	}
+	oldTemplates := msgTemplates
+	msgTemplates = tmpls
+	var out bytes.Buffer
+	dryRun = newDryRunRecorder(&out)
+	defer func() {
+		msgTemplates = oldTemplates
+		dryRun = nil
+	}()
+
+	// RedisClient 和 mysqlDB 均未初始化，演练时不应访问
+	for _, key := range []string{"ABC-1", "ABC-2"} {
+		PushEventArgumentsAndPhones(&eventArgs{
+			eventType:    EventUpdateStatus,
+			summaryKeyID: key,
+			summary:      "登录失败 " + key,
+			operator:     "张三",
+			statusFrom:   "进行中",
+			statusTo:     "完成",
+			link:         "https://jira.example.com/browse/" + key,
+			mentions:     []mention{{Mobile: "13800000000"}},
+			robots:       []string{"default", "qa"},
+		})
+	}
+	deliverNotification("qa", "sprint 已开始", nil)
+	dryRun.flush()
+
+	got := out.String()
+	if strings.Count(got, "--- 机器人 ") != 3 {
+		t.Fatalf("want 3 messages, got:\n%s", got)
+	}
+	if !strings.HasPrefix(got, "--- 机器人 qa\nsprint 已开始\n") {
+		t.Errorf("direct message not printed first:\n%s", got)
+	}
+	for _, want := range []string{"--- 机器人 default，@ 13800000000", "--- 机器人 qa，@ 13800000000", "ABC-1", "ABC-2"} {
+		if !strings.Contains(got, want) {
+			t.Errorf("output missing %q:\n%s", want, got)
+		}
+	}
+	if len(dryRun.buckets) != 0 {
+		t.Errorf("buckets not cleared after flush")
+	}
+}
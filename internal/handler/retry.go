@@ -0,0 +1,223 @@
+package handler
+
+import (
+	"database/sql"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"log"
+	"math/rand"
+	"strconv"
+	"time"
+	"whenchangesth/internal/conf"
+	"whenchangesth/internal/metrics"
+
+	"github.com/go-redis/redis/v8"
+)
+
+// 发送失败的消息保存在 Redis 中等待重试，队列为有序集合，分值为下次重试时间（毫秒）
+const (
+	retryQueueKey  = "dingtalk_retry_queue"
+	retryKeyPrefix = "dingtalk_retry:"
+	retrySeqKey    = "dingtalk_retry_seq"
+)
+
+// retryMessage 是一条等待重试的已渲染消息
+type retryMessage struct {
+	ID        string    `json:"id"`
+	Robot     string    `json:"robot"`
+	Content   string    `json:"content"`
+	Mentions  []string  `json:"mentions"`
+	Attempts  int       `json:"attempts"`
+	LastError string    `json:"last_error"`
+	CreatedAt time.Time `json:"created_at"`
+}
+
+// deliverNotification 经过限流发送钉钉通知，失败时放入重试队列，演练时只输出消息
+func deliverNotification(robot, content string, mentions []mention) {
+	deliverNotificationThen(robot, content, mentions, nil)
+}
+
+// deliverNotificationThen 与 deliverNotification 相同，消息发送成功或写入重试队列后以 true 调用 done，
+// 写入重试队列也失败时以 false 调用
+func deliverNotificationThen(robot, content string, mentions []mention, done func(saved bool)) {
+	if done == nil {
+		done = func(bool) {}
+	}
+	if dryRun != nil {
+		dryRun.print(robot, content, mentions)
+		done(true)
+		return
+	}
+	submitNotification(robot, content, mentions, func(err error) {
+		if err == nil {
+			done(true)
+			return
+		}
+		fmt.Printf("⚠️ 钉钉通知发送失败，稍后重试: %v\n", err)
+
+		m := &retryMessage{Robot: robot, Content: content, Attempts: 1, LastError: err.Error(), CreatedAt: time.Now()}
+		if errors.Is(err, errShuttingDown) {
+			// 没有真正发送，不计入尝试次数
+			m.Attempts = 0
+		}
+		for _, mt := range mentions {
+			if t := mt.token(); t != "" {
+				m.Mentions = append(m.Mentions, t)
+			}
+		}
+		if err := enqueueRetry(m); err != nil {
+			fmt.Printf("❌ 写入重试队列失败: %v\n", err)
+			done(false)
+			return
+		}
+		done(true)
+	})
+}
+
+// enqueueRetry 保存消息并按已尝试次数计算下次重试时间
+func enqueueRetry(m *retryMessage) error {
+	if m.ID == "" {
+		seq, err := RedisClient.Incr(ctx, retrySeqKey).Result()
+		if err != nil {
+			return err
+		}
+		m.ID = strconv.FormatInt(seq, 10)
+	}
+	data, err := json.Marshal(m)
+	if err != nil {
+		return err
+	}
+	if err := RedisClient.Set(ctx, retryKeyPrefix+m.ID, data, 0).Err(); err != nil {
+		return err
+	}
+	next := time.Now().Add(retryBackoff(appCfg.Retry, m.Attempts, rand.Int63n))
+	return RedisClient.ZAdd(ctx, retryQueueKey, &redis.Z{Score: float64(next.UnixMilli()), Member: m.ID}).Err()
+}
+
+// retryBackoff 返回第 attempts 次失败后的等待时间
+// 等待时间从 BaseDelay 开始翻倍，不超过 MaxDelay，并在后一半区间内随机抖动，避免多条消息同时重试
+func retryBackoff(cfg conf.RetryConfig, attempts int, randInt63n func(int64) int64) time.Duration {
+	d := cfg.BaseDelay
+	for i := 1; i < attempts && d < cfg.MaxDelay; i++ {
+		d *= 2
+	}
+	if d > cfg.MaxDelay {
+		d = cfg.MaxDelay
+	}
+	half := d / 2
+	if half <= 0 {
+		return d
+	}
+	return half + time.Duration(randInt63n(int64(half)+1))
+}
+
+// processDueRetries 重试已到期的消息，从队列中移除成功的实例负责重试
+func processDueRetries() {
+	due, err := RedisClient.ZRangeByScore(ctx, retryQueueKey, &redis.ZRangeBy{
+		Min: "-inf",
+		Max: strconv.FormatInt(time.Now().UnixMilli(), 10),
+	}).Result()
+	if err != nil {
+		fmt.Printf("⚠️ 读取重试队列失败: %v\n", err)
+		return
+	}
+	for _, id := range due {
+		removed, err := RedisClient.ZRem(ctx, retryQueueKey, id).Result()
+		if err != nil || removed == 0 {
+			continue
+		}
+		goBackground(func() { retryMessageByID(id) })
+	}
+}
+
+// retryMessageByID 重试一条消息，次数用尽时转入死信表
+func retryMessageByID(id string) {
+	data, err := RedisClient.Get(ctx, retryKeyPrefix+id).Bytes()
+	if err == redis.Nil {
+		// 已由其他实例处理完成
+		return
+	}
+	if err != nil {
+		fmt.Printf("⚠️ 读取重试消息 %s 失败: %v\n", id, err)
+		return
+	}
+	var m retryMessage
+	if err := json.Unmarshal(data, &m); err != nil {
+		fmt.Printf("⚠️ 解析重试消息 %s 失败: %v\n", id, err)
+		return
+	}
+
+	mentions := make([]mention, 0, len(m.Mentions))
+	for _, t := range m.Mentions {
+		mentions = append(mentions, parseMention(t))
+	}
+	m.Attempts++
+	submitNotification(m.Robot, m.Content, mentions, func(err error) {
+		switch {
+		case errors.Is(err, errShuttingDown):
+			m.Attempts--
+			if err := enqueueRetry(&m); err != nil {
+				fmt.Printf("❌ 写入重试队列失败，消息已丢弃: %v\n", err)
+			}
+			return
+		case err == nil:
+			log.Printf("重试消息 %s 第 %d 次发送成功", id, m.Attempts)
+		case m.Attempts >= appCfg.Retry.MaxAttempts:
+			m.LastError = err.Error()
+			if dlErr := saveDeadLetter(&m); dlErr != nil {
+				// 死信写入失败时继续留在重试队列中
+				fmt.Printf("❌ 写入死信表失败: %v\n", dlErr)
+				if err := enqueueRetry(&m); err != nil {
+					fmt.Printf("❌ 写入重试队列失败，消息已丢弃: %v\n", err)
+				}
+				return
+			}
+			metrics.DingTalkDeadLetters.WithLabelValues(m.Robot).Inc()
+			log.Printf("⚠️ 消息 %s 已尝试 %d 次，转入死信表: %v", id, m.Attempts, err)
+		default:
+			m.LastError = err.Error()
+			if err := enqueueRetry(&m); err != nil {
+				fmt.Printf("❌ 写入重试队列失败，消息已丢弃: %v\n", err)
+			}
+			return
+		}
+		_ = RedisClient.Del(ctx, retryKeyPrefix+id).Err()
+	})
+}
+
+// recoverOrphanRetries 将不在队列中的重试消息重新放回队列，通常是重试中途退出的实例留下的
+func recoverOrphanRetries() error {
+	var cursor uint64
+	for {
+		keys, next, err := RedisClient.Scan(ctx, cursor, retryKeyPrefix+"*", 100).Result()
+		if err != nil {
+			return fmt.Errorf("扫描重试消息失败: %v", err)
+		}
+		for _, key := range keys {
+			id := key[len(retryKeyPrefix):]
+			// 运行期间刚被取出的消息可能正在重试，推迟一个锁周期再处理
+			if err := RedisClient.ZAddNX(ctx, retryQueueKey, &redis.Z{
+				Score:  float64(time.Now().Add(lockTTL).UnixMilli()),
+				Member: id,
+			}).Err(); err != nil {
+				return fmt.Errorf("写入重试队列失败: %v", err)
+			}
+		}
+		if cursor = next; cursor == 0 {
+			return nil
+		}
+	}
+}
+
+// saveDeadLetter 将重试次数用尽的消息写入死信表
+func saveDeadLetter(m *retryMessage) error {
+	mentions, _ := json.Marshal(m.Mentions)
+	return writeDB("jirahook_dead_letter", func(db *sql.DB) error {
+		_, err := db.Exec(`
+            INSERT INTO jirahook_dead_letter (robot, content, mentions, attempts, last_error, created_at, dead_at)
+            VALUES (?, ?, ?, ?, ?, ?, ?)`,
+			m.Robot, m.Content, string(mentions), m.Attempts, m.LastError, m.CreatedAt, time.Now())
+		return err
+	})
+}
@@ -0,0 +1,32 @@
+package handler
+
+import (
+	"testing"
+	"time"
+	"whenchangesth/internal/conf"
+)
+
+func TestRetryBackoff(t *testing.T) {
+	cfg := conf.RetryConfig{MaxAttempts: 6, BaseDelay: 10 * time.Second, MaxDelay: time.Minute}
+	noJitter := func(int64) int64 { return 0 }
+	fullJitter := func(n int64) int64 { return n - 1 }
+
+	cases := []struct {
+		attempts int
+		want     time.Duration
+	}{
+		{1, 10 * time.Second},
+		{2, 20 * time.Second},
+		{3, 40 * time.Second},
+		{4, time.Minute},
+		{10, time.Minute},
+	}
+	for _, c := range cases {
+		if got := retryBackoff(cfg, c.attempts, noJitter); got != c.want/2 {
+			t.Errorf("attempts %d: min backoff = %v, want %v", c.attempts, got, c.want/2)
+		}
+		if got := retryBackoff(cfg, c.attempts, fullJitter); got != c.want {
+			t.Errorf("attempts %d: max backoff = %v, want %v", c.attempts, got, c.want)
+		}
+	}
+}
@@ -0,0 +1,66 @@
+package handler
+
+import (
+	"whenchangesth/internal/conf"
+	"whenchangesth/internal/objects"
+)
+
+// routeRobots 根据路由表返回事件需要发送到的机器人，没有路由命中时返回默认机器人
+func routeRobots(routes []conf.RouteConfig, issue *objects.Issue) []string {
+	var robots []string
+	seen := make(map[string]bool)
+	for _, route := range routes {
+		if !routeMatches(route, issue) {
+			continue
+		}
+		for _, name := range route.Robots {
+			if !seen[name] {
+				seen[name] = true
+				robots = append(robots, name)
+			}
+		}
+	}
+	if len(robots) == 0 {
+		robots = []string{conf.DefaultRobot}
+	}
+	return robots
+}
+
+// routeMatches 判断问题是否满足路由条件
+func routeMatches(route conf.RouteConfig, issue *objects.Issue) bool {
+	var project, issueType string
+	var components, labels []string
+	if issue != nil && issue.Fields != nil {
+		fields := issue.Fields
+		if fields.Project != nil {
+			project = fields.Project.Key
+		}
+		if fields.Type != nil {
+			issueType = fields.Type.Name
+		}
+		for _, c := range fields.Components {
+			components = append(components, c.Name)
+		}
+		labels = fields.Labels
+	}
+
+	return matchAny(route.Projects, project) &&
+		matchAny(route.IssueTypes, issueType) &&
+		matchAny(route.Components, components...) &&
+		matchAny(route.Labels, labels...)
+}
+
+// matchAny 在 want 为空时视为命中，否则要求 values 中至少有一个在 want 内
+func matchAny(want []string, values ...string) bool {
+	if len(want) == 0 {
+		return true
+	}
+	for _, w := range want {
+		for _, v := range values {
+			if w == v {
+				return true
+			}
+		}
+	}
+	return false
+}
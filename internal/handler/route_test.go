@@ -0,0 +1,55 @@
+package handler
+
+import (
+	"reflect"
+	"testing"
+	"whenchangesth/internal/conf"
+	"whenchangesth/internal/objects"
+)
+
+func TestRouteRobots(t *testing.T) {
+	routes := []conf.RouteConfig{
+		{Projects: []string{"PAY"}, Robots: []string{"pay"}},
+		{IssueTypes: []string{"Bug", "故障"}, Robots: []string{"qa"}},
+		{Projects: []string{"APP"}, Components: []string{"iOS", "Android"}, Robots: []string{"mobile"}},
+		{Labels: []string{"urgent"}, Robots: []string{"oncall", "pay"}},
+	}
+	issue := func(project, issueType string, components, labels []string) *objects.Issue {
+		fields := &objects.IssueFields{
+			Project: &objects.Project{Key: project},
+			Type:    &objects.IssueType{Name: issueType},
+			Labels:  labels,
+		}
+		for _, c := range components {
+			fields.Components = append(fields.Components, &objects.Component{Name: c})
+		}
+		return &objects.Issue{Key: project + "-1", Fields: fields}
+	}
+
+	cases := []struct {
+		name  string
+		issue *objects.Issue
+		want  []string
+	}{
+		{"project", issue("PAY", "Task", nil, nil), []string{"pay"}},
+		{"issue type", issue("OPS", "故障", nil, nil), []string{"qa"}},
+		{"component with project", issue("APP", "Task", []string{"Web", "Android"}, nil), []string{"mobile"}},
+		{"component without project", issue("WEB", "Task", []string{"Android"}, nil), []string{conf.DefaultRobot}},
+		{"label", issue("OPS", "Task", nil, []string{"backend", "urgent"}), []string{"oncall", "pay"}},
+		{"several robots without duplicates", issue("PAY", "Bug", nil, []string{"urgent"}), []string{"pay", "qa", "oncall"}},
+		{"no match falls back to default", issue("OPS", "Task", nil, nil), []string{conf.DefaultRobot}},
+		{"issue without fields", &objects.Issue{Key: "X-1"}, []string{conf.DefaultRobot}},
+		{"nil issue", nil, []string{conf.DefaultRobot}},
+	}
+	for _, tc := range cases {
+		t.Run(tc.name, func(t *testing.T) {
+			if got := routeRobots(routes, tc.issue); !reflect.DeepEqual(got, tc.want) {
+				t.Errorf("routeRobots() = %v, want %v", got, tc.want)
+			}
+		})
+	}
+
+	if got := routeRobots(nil, issue("PAY", "Bug", nil, nil)); !reflect.DeepEqual(got, []string{conf.DefaultRobot}) {
+		t.Errorf("empty routes = %v, want default", got)
+	}
+}
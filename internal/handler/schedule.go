@@ -0,0 +1,192 @@
+package handler
+
+import (
+	"context"
+	"fmt"
+	"log"
+	"slices"
+	"strconv"
+	"strings"
+	"time"
+	"whenchangesth/internal/conf"
+
+	"github.com/go-redis/redis/v8"
+)
+
+// 去抖桶的发送计划保存在 Redis 有序集合中，成员为汇总 Key，分值为发送时间（毫秒）
+const (
+	scheduleKey       = "issue_event_schedule"
+	summaryKeyPrefix  = "issue_event_summary:"
+	schedulerInterval = time.Second
+	recoverInterval   = time.Minute
+)
+
+// deferFlushScript 将去抖桶的发送时间设为 ARGV[2]，计划已到期（不晚于 ARGV[3]）时保持不变
+var deferFlushScript = redis.NewScript(`
+local due = redis.call('ZSCORE', KEYS[1], ARGV[1])
+if due and tonumber(due) <= tonumber(ARGV[3]) then
+    return 0
+end
+redis.call('ZADD', KEYS[1], ARGV[2], ARGV[1])
+return 1`)
+
+// scheduleFlush 设置去抖桶的发送时间，未到期的计划推迟到新的时间
+// 已到期的计划保持不变，避免随后写入的普通事件推迟需要立即发送的桶
+func scheduleFlush(summaryKey string, at time.Time) error {
+	return deferFlushScript.Run(ctx, RedisClient, []string{scheduleKey},
+		summaryKey, at.UnixMilli(), time.Now().UnixMilli()).Err()
+}
+
+// scheduleFlushBy 将去抖桶的发送时间提前到 at，已有更早的计划时保持不变
+func scheduleFlushBy(summaryKey string, at time.Time) error {
+	return RedisClient.ZAddArgs(ctx, scheduleKey, redis.ZAddArgs{
+		LT:      true,
+		Members: []redis.Z{{Score: float64(at.UnixMilli()), Member: summaryKey}},
+	}).Err()
+}
+
+// scheduleFlushIfAbsent 仅在去抖桶没有发送计划时设置发送时间，返回是否新增了计划
+func scheduleFlushIfAbsent(summaryKey string, at time.Time) (int, error) {
+	added, err := RedisClient.ZAddNX(ctx, scheduleKey, &redis.Z{
+		Score:  float64(at.UnixMilli()),
+		Member: summaryKey,
+	}).Result()
+	return int(added), err
+}
+
+// legacyEventTypes 是旧版本使用的事件类型，旧版本的汇总 Key 不含机器人，格式为 issue_event_summary:<事件类型>:<操作人>
+var legacyEventTypes = []string{EventCreate, EventDelete, EventUpdateReport, EventUpdateAssigner, EventUpdateStatus}
+
+// mergeLegacyScript 将旧版本的去抖桶并入 default 机器人的去抖桶，返回并入的事件数
+var mergeLegacyScript = redis.NewScript(`
+local items = redis.call('LRANGE', KEYS[1], 0, -1)
+for _, item in ipairs(items) do
+    redis.call('RPUSH', KEYS[3], item)
+end
+if redis.call('EXISTS', KEYS[2]) == 1 then
+    redis.call('SUNIONSTORE', KEYS[4], KEYS[4], KEYS[2])
+    redis.call('EXPIRE', KEYS[4], ARGV[1])
+end
+if #items > 0 then
+    redis.call('EXPIRE', KEYS[3], ARGV[1])
+end
+redis.call('DEL', KEYS[1], KEYS[2])
+return #items`)
+
+// parseSummaryKey 从汇总 Key 中解析机器人、事件类型和操作人，操作人中可能含有冒号
+// 旧版本不含机器人的 Key 归属 default 机器人
+func parseSummaryKey(summaryKey string) (robot, eventType, operator string, ok bool) {
+	rest, found := strings.CutPrefix(summaryKey, summaryKeyPrefix)
+	if !found {
+		return "", "", "", false
+	}
+	if eventType, operator, found := strings.Cut(rest, ":"); found && slices.Contains(legacyEventTypes, eventType) {
+		return conf.DefaultRobot, eventType, operator, true
+	}
+	parts := strings.SplitN(rest, ":", 3)
+	if len(parts) != 3 {
+		return "", "", "", false
+	}
+	return parts[0], parts[1], parts[2], true
+}
+
+// mergeLegacyBucket 将旧版本留下的去抖桶并入 default 机器人的去抖桶，返回新的汇总 Key
+func mergeLegacyBucket(legacyKey, eventType, operator string) (string, error) {
+	summaryKey, phoneKey := bucketKeys(conf.DefaultRobot, eventType, operator)
+	legacyPhoneKey := "issue_event_phone:" + strings.TrimPrefix(legacyKey, summaryKeyPrefix)
+	n, err := mergeLegacyScript.Run(ctx, RedisClient,
+		[]string{legacyKey, legacyPhoneKey, summaryKey, phoneKey}, int(redisTTL.Seconds())).Int()
+	if err != nil {
+		return "", fmt.Errorf("迁移旧版本去抖桶 %s 失败: %v", legacyKey, err)
+	}
+	log.Printf("已将旧版本去抖桶 %s 的 %d 条事件并入 %s", legacyKey, n, summaryKey)
+	return summaryKey, nil
+}
+
+// runFlushScheduler 定期发送已到期的去抖桶，并定期恢复其他实例遗留的桶，ctx 结束时退出
+func runFlushScheduler(ctx context.Context) {
+	ticker := time.NewTicker(schedulerInterval)
+	defer ticker.Stop()
+	recoverTicker := time.NewTicker(recoverInterval)
+	defer recoverTicker.Stop()
+	for {
+		select {
+		case <-ctx.Done():
+			return
+		case <-ticker.C:
+			flushDueBuckets()
+			processDueRetries()
+		case <-recoverTicker.C:
+			// 运行期间发现的桶可能刚写入、尚未写入计划，按正常的去抖延迟发送
+			if err := recoverOrphanBuckets(timerDelay); err != nil {
+				log.Printf("⚠️ %v", err)
+			}
+			if err := recoverOrphanClaims(); err != nil {
+				log.Printf("⚠️ %v", err)
+			}
+			if err := recoverOrphanRetries(); err != nil {
+				log.Printf("⚠️ %v", err)
+			}
+		}
+	}
+}
+
+// flushDueBuckets 取出到期的发送计划并发送，从集合中移除成功的实例负责认领
+func flushDueBuckets() {
+	due, err := RedisClient.ZRangeByScore(ctx, scheduleKey, &redis.ZRangeBy{
+		Min: "-inf",
+		Max: strconv.FormatInt(time.Now().UnixMilli(), 10),
+	}).Result()
+	if err != nil {
+		fmt.Printf("⚠️ 读取发送计划失败: %v\n", err)
+		return
+	}
+	for _, summaryKey := range due {
+		removed, err := RedisClient.ZRem(ctx, scheduleKey, summaryKey).Result()
+		if err != nil || removed == 0 {
+			continue
+		}
+		robot, eventType, operator, ok := parseSummaryKey(summaryKey)
+		if !ok {
+			log.Printf("⚠️ 无法解析的汇总 Key: %s", summaryKey)
+			continue
+		}
+		goBackground(func() { flushBucket(robot, eventType, operator) })
+	}
+}
+
+// recoverOrphanBuckets 为没有发送计划的去抖桶补上计划，在 delay 之后发送
+// 启动时以 0 调用，上次进程退出前未发送的事件会在启动后立即发送，旧版本留下的去抖桶先并入 default 机器人
+func recoverOrphanBuckets(delay time.Duration) error {
+	var cursor uint64
+	recovered := 0
+	for {
+		keys, next, err := RedisClient.Scan(ctx, cursor, summaryKeyPrefix+"*", 100).Result()
+		if err != nil {
+			return fmt.Errorf("扫描去抖桶失败: %v", err)
+		}
+		for _, key := range keys {
+			robot, eventType, operator, ok := parseSummaryKey(key)
+			if !ok {
+				continue
+			}
+			if newKey, _ := bucketKeys(robot, eventType, operator); newKey != key {
+				if key, err = mergeLegacyBucket(key, eventType, operator); err != nil {
+					return err
+				}
+			}
+			added, err := scheduleFlushIfAbsent(key, time.Now().Add(delay))
+			if err != nil {
+				return fmt.Errorf("写入发送计划失败: %v", err)
+			}
+			recovered += added
+		}
+		if cursor = next; cursor == 0 {
+			break
+		}
+	}
+	if recovered > 0 {
+		log.Printf("已恢复 %d 个未发送的去抖桶", recovered)
+	}
+	return nil
+}
@@ -0,0 +1,210 @@
+package handler
+
+import (
+	"context"
+	"strings"
+	"sync"
+	"testing"
+	"time"
+	"whenchangesth/internal/conf"
+	"whenchangesth/internal/render"
+
+	"github.com/alicebob/miniredis/v2"
+	"github.com/go-redis/redis/v8"
+)
+
+// useMiniRedis 在测试期间将 RedisClient 指向内存中的 Redis
+func useMiniRedis(t *testing.T) *miniredis.Miniredis {
+	t.Helper()
+	mr := miniredis.RunT(t)
+	prev := RedisClient
+	RedisClient = redis.NewClient(&redis.Options{Addr: mr.Addr()})
+	t.Cleanup(func() {
+		RedisClient.Close()
+		RedisClient = prev
+	})
+	return mr
+}
+
+// stubDelivery 在测试期间用内置模板渲染 robot 的消息，并交给 send 发送
+func stubDelivery(t *testing.T, robot string, send func(content string, mentions []mention) error) {
+	t.Helper()
+	tmpls, err := render.Load("", []string{robot})
+	if err != nil {
+		t.Fatal(err)
+	}
+	prevCfg, prevTemplates := appCfg, msgTemplates
+	appCfg, msgTemplates = &conf.Config{}, tmpls
+	sendQueuesMu.Lock()
+	sendQueues[robot] = &robotQueue{
+		robot: robot,
+		ctx:   context.Background(),
+		take:  func(string) (time.Duration, error) { return 0, nil },
+		send: func(_, content string, mentions []mention) error {
+			return send(content, mentions)
+		},
+	}
+	sendQueuesMu.Unlock()
+	t.Cleanup(func() {
+		background.Wait()
+		appCfg, msgTemplates = prevCfg, prevTemplates
+		sendQueuesMu.Lock()
+		delete(sendQueues, robot)
+		sendQueuesMu.Unlock()
+	})
+}
+
+// sentLog 记录发送的消息
+type sentLog struct {
+	mu       sync.Mutex
+	contents []string
+}
+
+func (l *sentLog) send(content string, _ []mention) error {
+	l.mu.Lock()
+	defer l.mu.Unlock()
+	l.contents = append(l.contents, content)
+	return nil
+}
+
+func (l *sentLog) sent() []string {
+	l.mu.Lock()
+	defer l.mu.Unlock()
+	return append([]string(nil), l.contents...)
+}
+
+// flushAt 返回去抖桶的发送时间
+func flushAt(t *testing.T, summaryKey string) time.Time {
+	t.Helper()
+	score, err := RedisClient.ZScore(ctx, scheduleKey, summaryKey).Result()
+	if err != nil {
+		t.Fatalf("ZSCORE %s: %v", summaryKey, err)
+	}
+	return time.UnixMilli(int64(score))
+}
+
+func TestParseSummaryKey(t *testing.T) {
+	summaryKey, _ := bucketKeys("qa", EventUpdateStatus, "张三:外包")
+	robot, eventType, operator, ok := parseSummaryKey(summaryKey)
+	if !ok || robot != "qa" || eventType != EventUpdateStatus || operator != "张三:外包" {
+		t.Fatalf("parseSummaryKey(%q) = %q, %q, %q, %v", summaryKey, robot, eventType, operator, ok)
+	}
+
+	// 旧版本不含机器人的 Key 归属 default 机器人
+	robot, eventType, operator, ok = parseSummaryKey("issue_event_summary:updated_status:张三:外包")
+	if !ok || robot != conf.DefaultRobot || eventType != EventUpdateStatus || operator != "张三:外包" {
+		t.Errorf("legacy key = %q, %q, %q, %v", robot, eventType, operator, ok)
+	}
+	if _, _, _, ok := parseSummaryKey("issue_event_summary:created"); ok {
+		t.Error("expected key without operator to be rejected")
+	}
+}
+
+func TestImmediateEventNotDeferred(t *testing.T) {
+	useMiniRedis(t)
+	push := func(field string, immediate bool) {
+		PushEventArgumentsAndPhones(&eventArgs{
+			eventType: EventUpdateField, operator: "张三", summaryKeyID: "ABC-1",
+			field: field, robots: []string{"team"}, immediate: immediate,
+		})
+	}
+	summaryKey, _ := bucketKeys("team", EventUpdateField, "张三")
+
+	// 同一次变更中先有立即发送的优先级变更，后有普通的摘要变更
+	push("priority", true)
+	push("summary", false)
+	if at := flushAt(t, summaryKey); at.After(time.Now()) {
+		t.Errorf("bucket deferred to %v after a normal push, want due now", at)
+	}
+
+	// 普通事件在先时，立即发送的事件将计划提前
+	RedisClient.Del(ctx, scheduleKey)
+	push("summary", false)
+	if at := flushAt(t, summaryKey); at.Before(time.Now().Add(timerDelay / 2)) {
+		t.Fatalf("normal push scheduled at %v, want about now+%v", at, timerDelay)
+	}
+	push("priority", true)
+	if at := flushAt(t, summaryKey); at.After(time.Now()) {
+		t.Errorf("immediate push left bucket at %v, want due now", at)
+	}
+}
+
+func TestScheduleFlushIfAbsent(t *testing.T) {
+	useMiniRedis(t)
+	first := time.Now().Add(time.Minute)
+	if added, err := scheduleFlushIfAbsent("k", first); err != nil || added != 1 {
+		t.Fatalf("first = %d, %v", added, err)
+	}
+	if added, err := scheduleFlushIfAbsent("k", time.Now()); err != nil || added != 0 {
+		t.Fatalf("second = %d, %v", added, err)
+	}
+	if at := flushAt(t, "k"); at.UnixMilli() != first.UnixMilli() {
+		t.Errorf("existing plan moved to %v", at)
+	}
+}
+
+func TestFlushDueBucketsOnlyDue(t *testing.T) {
+	useMiniRedis(t)
+	var log sentLog
+	stubDelivery(t, "team", log.send)
+
+	PushEventArgumentsAndPhones(&eventArgs{eventType: EventCreate, operator: "张三", summaryKeyID: "ABC-1", robots: []string{"team"}})
+	PushEventArgumentsAndPhones(&eventArgs{eventType: EventDelete, operator: "张三", summaryKeyID: "ABC-2", robots: []string{"team"}, immediate: true})
+	laterKey, _ := bucketKeys("team", EventCreate, "张三")
+	dueKey, _ := bucketKeys("team", EventDelete, "张三")
+
+	flushDueBuckets()
+	background.Wait()
+
+	sent := log.sent()
+	if len(sent) != 1 || !strings.Contains(sent[0], "ABC-2") {
+		t.Fatalf("sent = %q, want only the due bucket", sent)
+	}
+	if RedisClient.Exists(ctx, dueKey).Val() != 0 {
+		t.Error("due bucket not removed after send")
+	}
+	if _, err := RedisClient.ZScore(ctx, scheduleKey, dueKey).Result(); err != redis.Nil {
+		t.Errorf("due plan still scheduled: %v", err)
+	}
+	if RedisClient.LLen(ctx, laterKey).Val() != 1 || flushAt(t, laterKey).Before(time.Now()) {
+		t.Error("bucket that is not due yet was flushed")
+	}
+}
+
+func TestRecoverOrphanBuckets(t *testing.T) {
+	useMiniRedis(t)
+	orphanKey, _ := bucketKeys("team", EventCreate, "张三")
+	RedisClient.RPush(ctx, orphanKey, `{"summaryKeyID":"ABC-1"}`)
+	RedisClient.RPush(ctx, "issue_event_summary:updated_status:李四", `{"summaryKeyID":"ABC-2"}`)
+	RedisClient.SAdd(ctx, "issue_event_phone:updated_status:李四", "138")
+
+	if err := recoverOrphanBuckets(0); err != nil {
+		t.Fatal(err)
+	}
+
+	if at := flushAt(t, orphanKey); at.After(time.Now()) {
+		t.Errorf("orphan scheduled at %v, want now", at)
+	}
+	// 旧版本的去抖桶并入 default 机器人后再补上计划
+	mergedKey, mergedPhones := bucketKeys(conf.DefaultRobot, EventUpdateStatus, "李四")
+	if items := RedisClient.LRange(ctx, mergedKey, 0, -1).Val(); len(items) != 1 || !strings.Contains(items[0], "ABC-2") {
+		t.Errorf("merged bucket = %q", items)
+	}
+	if phones := RedisClient.SMembers(ctx, mergedPhones).Val(); len(phones) != 1 || phones[0] != "138" {
+		t.Errorf("merged phones = %q", phones)
+	}
+	if n := RedisClient.Exists(ctx, "issue_event_summary:updated_status:李四", "issue_event_phone:updated_status:李四").Val(); n != 0 {
+		t.Errorf("%d legacy keys left", n)
+	}
+	flushAt(t, mergedKey)
+
+	// 已有计划的桶保持原计划
+	later := time.Now().Add(time.Hour)
+	RedisClient.ZAdd(ctx, scheduleKey, &redis.Z{Score: float64(later.UnixMilli()), Member: orphanKey})
+	if err := recoverOrphanBuckets(0); err != nil {
+		t.Fatal(err)
+	}
+	if at := flushAt(t, orphanKey); at.UnixMilli() != later.UnixMilli() {
+		t.Errorf("existing plan moved to %v", at)
+	}
+}
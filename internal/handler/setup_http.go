@@ -2,56 +2,231 @@ package handler
 
 import (
 	"context"
+	"database/sql"
 	"fmt"
 	"github.com/gin-gonic/gin"
 	"github.com/go-redis/redis/v8"
+	"github.com/prometheus/client_golang/prometheus"
+	"github.com/prometheus/client_golang/prometheus/promhttp"
 	"log"
+	"net/http"
+	"os/signal"
+	"sync/atomic"
+	"syscall"
+	"time"
 	"whenchangesth/internal/conf"
+	"whenchangesth/internal/render"
+	"whenchangesth/internal/store"
 )
 
 var (
-	dingCfg *conf.DingBotStr
-	rdsCfg  *conf.RedisConfig
-	err     error
+	appCfg *conf.Config
+
+	phoneBook    *conf.PhoneBook
+	msgTemplates *render.Templates
 
 	RedisClient *redis.Client
+	mysqlDB     *sql.DB
 	ctx         = context.Background()
 )
 
-func init() {
-	// 解析钉钉配置
-	dingCfg, err = conf.ParseDingConfig()
-	if err != nil {
-		log.Fatal("DingTalk配置解析失败:", err)
+// mysqlRetryInterval 是启动时 MySQL 不可用的情况下检查其是否恢复的间隔
+const mysqlRetryInterval = 10 * time.Second
+
+// Init 使用启动时加载的配置初始化各依赖
+func Init(cfg *conf.Config) error {
+	if err := loadMessaging(cfg); err != nil {
+		return err
 	}
+	// 监听通讯录变更
+	goLoop(func(c context.Context) { phoneBook.Watch(c, cfg.Phone.ReloadInterval) })
 
-	// 解析 Redis 配置
-	rdsCfg, err = conf.ParseRedisConfig()
-	if err != nil {
-		log.Fatal("Redis配置解析失败:", err)
+	if err := initStorage(cfg); err != nil {
+		return err
+	}
+	prometheus.MustRegister(newStateCollector())
+
+	// 按配置执行未应用的迁移，MySQL 不可用时同样以降级状态启动，由 /readyz 报告，恢复后再执行迁移
+	db := mysqlDB
+	if err := db.PingContext(ctx); err != nil {
+		log.Printf("⚠️ MySQL 连接失败，以降级状态启动: %v", err)
+		if cfg.MySQL.AutoMigrate {
+			setMigrationPending(fmt.Errorf("MySQL 不可用，尚未执行数据库迁移"))
+			goLoop(func(c context.Context) { migrateWhenReady(c, db) })
+		}
+	} else if cfg.MySQL.AutoMigrate {
+		if err := migrate(ctx, db); err != nil {
+			return err
+		}
 	}
 
-	// 初始化 Redis 客户端
+	// 启动 webhook worker
+	webhookPool = newWorkPool(cfg.Server.QueueSize, cfg.Server.Workers, processWebhook)
+	webhookPool.start()
+
+	// 恢复上次退出时未发送的去抖桶，并启动发送计划轮询
+	goLoop(recoverOnBoot)
+	goLoop(runFlushScheduler)
+
+	if cfg.Timesheet.Enabled {
+		goLoop(func(c context.Context) { runTimesheet(c, cfg.Timesheet) })
+	}
+	if cfg.Archive.Enabled && cfg.Archive.Retention > 0 {
+		goLoop(func(c context.Context) { runArchiveCleanup(c, cfg.Archive.Retention) })
+	}
+	return nil
+}
+
+// initStorage 创建 Redis 客户端和 MySQL 连接池
+func initStorage(cfg *conf.Config) error {
 	RedisClient = redis.NewClient(&redis.Options{
-		Addr:     fmt.Sprintf("%s:%s", rdsCfg.Addr, rdsCfg.Port),
-		Password: rdsCfg.Password,
-		DB:       rdsCfg.DB,
+		Addr:     fmt.Sprintf("%s:%s", cfg.Redis.Addr, cfg.Redis.Port),
+		Password: cfg.Redis.Password,
+		DB:       cfg.Redis.DB,
 	})
+	RedisClient.AddHook(redisMetricsHook{})
 
-	// 测试连接
+	// 测试连接，Redis 不可用时以降级状态启动，由 /readyz 报告，恢复后自动继续
 	if _, err := RedisClient.Ping(ctx).Result(); err != nil {
-		log.Fatalf("Redis 连接失败: %v", err)
+		log.Printf("⚠️ Redis 连接失败，以降级状态启动: %v", err)
+	} else {
+		log.Printf("Redis 已连接: %v", RedisClient)
+	}
+
+	db, err := store.Connect(cfg.MySQL)
+	if err != nil {
+		return fmt.Errorf("MySQL 连接失败: %v", err)
+	}
+	mysqlDB = db
+	return nil
+}
+
+// migrate 执行未应用的数据库迁移
+func migrate(c context.Context, db *sql.DB) error {
+	applied, err := store.Migrate(c, db)
+	for _, m := range applied {
+		log.Printf("已执行数据库迁移 %04d_%s", m.Version, m.Name)
+	}
+	if err != nil {
+		return fmt.Errorf("数据库迁移失败: %v", err)
+	}
+	return nil
+}
+
+// migrateWhenReady 每隔 mysqlRetryInterval 检查一次 MySQL，可用时执行迁移，直到迁移成功或 c 结束
+// 迁移失败（例如其他实例正持有迁移锁）时继续重试，期间 /readyz 报告 MySQL 不可用
+func migrateWhenReady(c context.Context, db *sql.DB) {
+	ticker := time.NewTicker(mysqlRetryInterval)
+	defer ticker.Stop()
+	for {
+		select {
+		case <-c.Done():
+			return
+		case <-ticker.C:
+		}
+		if db.PingContext(c) != nil {
+			continue
+		}
+		if err := migrate(c, db); err != nil {
+			log.Printf("❌ %v，%v 后重试", err, mysqlRetryInterval)
+			setMigrationPending(err)
+			continue
+		}
+		setMigrationPending(nil)
+		return
+	}
+}
+
+// migrationPending 记录自动迁移尚未完成的原因，迁移完成后为 nil
+var migrationPending atomic.Pointer[error]
+
+// setMigrationPending 记录自动迁移尚未完成的原因，err 为 nil 表示已完成
+func setMigrationPending(err error) {
+	if err == nil {
+		migrationPending.Store(nil)
+		return
+	}
+	migrationPending.Store(&err)
+}
+
+// loadMessaging 加载渲染和发送消息所需的配置、消息模板和通讯录
+func loadMessaging(cfg *conf.Config) error {
+	appCfg = cfg
+
+	// 加载消息模板，格式有误时拒绝启动
+	tmpls, err := render.Load(cfg.Templates.Dir, cfg.RobotNames())
+	if err != nil {
+		return fmt.Errorf("消息模板加载失败: %v", err)
+	}
+	msgTemplates = tmpls
+
+	pb, err := conf.NewPhoneBook(cfg.Phone.File)
+	if err != nil {
+		return fmt.Errorf("通讯录加载失败: %v", err)
 	}
-	log.Printf("Redis 已连接: %v", RedisClient)
+	phoneBook = pb
+	return nil
 }
 
-func SetupHTTP() {
+func SetupHTTP(cfg *conf.Config) error {
+	if err := Init(cfg); err != nil {
+		return err
+	}
+
 	// 创建 Gin 引擎
 	r := gin.Default()
 
 	// 注册路由
-	r.POST("/jira/webhook", JiraWebhookHandler)
+	r.POST("/jira/webhook", VerifyWebhook(cfg.Webhook), JiraWebhookHandler)
+	r.GET("/healthz", HealthzHandler)
+	r.GET("/readyz", ReadyzHandler)
+	r.GET("/webhook/status", WebhookStatusHandler)
+	r.GET("/phonebook/status", PhoneBookStatusHandler)
+	r.GET("/dingtalk/status", DingTalkStatusHandler)
+	r.GET("/metrics", gin.WrapH(promhttp.Handler()))
+
+	// 只读查询接口返回操作人、问题和摘要，需要令牌
+	read := r.Group("", ReadAuth(cfg.Admin))
+	read.GET("/versions/:id/release-notes", ReleaseNotesHandler)
+	read.GET("/events", EventsHandler)
+	read.GET("/issues/:key/timeline", IssueTimelineHandler)
+
+	admin := r.Group("/admin", AdminAuth(cfg.Admin))
+	admin.GET("/dead-letters", DeadLettersHandler)
+	admin.POST("/dead-letters/:id/resend", ResendDeadLetterHandler)
+
+	// 启动 HTTP 服务，收到 SIGINT 或 SIGTERM 时优雅退出
+	srv := &http.Server{Addr: cfg.Server.Addr, Handler: r}
+	sigCtx, stop := signal.NotifyContext(context.Background(), syscall.SIGINT, syscall.SIGTERM)
+	defer stop()
+
+	serveErr := make(chan error, 1)
+	go func() { serveErr <- srv.ListenAndServe() }()
+	select {
+	case err := <-serveErr:
+		return err
+	case <-sigCtx.Done():
+	}
+
+	log.Printf("收到退出信号，停止接收 webhook，最长等待 %v", cfg.Server.ShutdownTimeout)
+	shutdownCtx, cancel := context.WithTimeout(context.Background(), cfg.Server.ShutdownTimeout)
+	defer cancel()
+	if err := srv.Shutdown(shutdownCtx); err != nil {
+		log.Printf("⚠️ 等待进行中的请求超时: %v", err)
+	}
+	if err := Shutdown(shutdownCtx); err != nil {
+		return err
+	}
+	log.Printf("已退出")
+	return nil
+}
+
+// PhoneBookStatusHandler 返回通讯录的重新加载次数和最近加载时间
+func PhoneBookStatusHandler(c *gin.Context) {
+	c.JSON(http.StatusOK, phoneBook.Stats())
+}
 
-	// 启动 HTTP 服务
-	r.Run(":4165")
+// DingTalkStatusHandler 返回各机器人发送队列的排队数量、等待时间和合并次数
+func DingTalkStatusHandler(c *gin.Context) {
+	c.JSON(http.StatusOK, gin.H{"robots": sendQueueStats()})
 }
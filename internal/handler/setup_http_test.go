@@ -1,7 +1,25 @@
 package handler
 
-import "testing"
+import (
+	"os"
+	"testing"
+	"whenchangesth/internal/conf"
+)
 
-func TestRunHook(*testing.T) {
-	SetupHTTP()
+func TestRunHook(t *testing.T) {
+	filePath := os.Getenv("JIRAHOOK_TEST_CONFIG")
+	if filePath == "" {
+		t.Skip("JIRAHOOK_TEST_CONFIG 未设置，跳过本地联调")
+	}
+
+	cfg, err := conf.Load(filePath)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if err := cfg.Validate(); err != nil {
+		t.Fatal(err)
+	}
+	if err := SetupHTTP(cfg); err != nil {
+		t.Fatal(err)
+	}
 }
@@ -0,0 +1,71 @@
+package handler
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"sync"
+)
+
+var (
+	// runCtx 在退出时取消，用于停止轮询和唤醒等待中的发送队列
+	runCtx, stopRunning = context.WithCancel(context.Background())
+
+	// background 记录退出前需要等待完成的发送和 MySQL 写入
+	background sync.WaitGroup
+
+	// loops 记录随 runCtx 停止的轮询，轮询会启动 background 任务，退出时需要先等轮询结束
+	loops sync.WaitGroup
+)
+
+// errShuttingDown 表示服务正在退出，消息没有发送而是转入重试队列
+var errShuttingDown = errors.New("服务正在退出")
+
+// goBackground 启动一个退出时需要等待完成的后台任务
+func goBackground(fn func()) {
+	background.Add(1)
+	go func() {
+		defer background.Done()
+		fn()
+	}()
+}
+
+// goLoop 启动一个随 runCtx 停止的轮询
+func goLoop(fn func(c context.Context)) {
+	loops.Add(1)
+	go func() {
+		defer loops.Done()
+		fn(runCtx)
+	}()
+}
+
+// Shutdown 停止轮询，处理完队列中的 webhook，限流队列中等待额度的消息转入重试队列，并等待进行中的发送和 MySQL 写入
+// 去抖桶及其发送时间保存在 Redis 中，未到期的桶由其他实例或下次启动后按时发送
+// c 结束时不再等待，返回错误，此时仍有任务在使用 Redis 和 MySQL，不关闭连接
+func Shutdown(c context.Context) error {
+	stopRunning()
+
+	// 轮询结束后不会再启动新的 background 任务，之后再关闭 webhook 队列并等待
+	done := make(chan struct{})
+	go func() {
+		loops.Wait()
+		if webhookPool != nil {
+			webhookPool.close()
+		}
+		background.Wait()
+		close(done)
+	}()
+	select {
+	case <-done:
+	case <-c.Done():
+		return fmt.Errorf("等待进行中的发送和写入超时: %v", c.Err())
+	}
+
+	if mysqlDB != nil {
+		mysqlDB.Close()
+	}
+	if RedisClient != nil {
+		RedisClient.Close()
+	}
+	return nil
+}
@@ -0,0 +1,173 @@
+package handler
+
+import (
+	"database/sql"
+	"fmt"
+	"slices"
+	"strconv"
+	"strings"
+	"time"
+	"whenchangesth/internal/objects"
+	"whenchangesth/internal/rules"
+)
+
+// saveIssueSnapshot 记录问题的最新状态、所属 Sprint 和修复版本，供 Sprint 报告和发布说明使用
+// 独立评论事件中的问题字段不完整，不更新快照
+func saveIssueSnapshot(e issueEvent) error {
+	if e.issue == nil || e.issue.ID == "" || e.issue.Fields == nil || e.comment != nil {
+		return nil
+	}
+	fields := e.fields()
+	now := time.Now()
+
+	return writeDB("jirahook_issue", func(db *sql.DB) error {
+
+		var issueType, status string
+		if fields.Type != nil {
+			issueType = fields.Type.Name
+		}
+		if fields.Status != nil {
+			status = fields.Status.Name
+		}
+		_, err := db.Exec(`
+            INSERT INTO jirahook_issue
+            (issue_id, issue_key, summary, issue_type, status, done, assignee, self, deleted, updated_at)
+            VALUES (?, ?, ?, ?, ?, ?, ?, ?, ?, ?)
+            ON DUPLICATE KEY UPDATE
+                issue_key = VALUES(issue_key), summary = VALUES(summary), issue_type = VALUES(issue_type),
+                status = VALUES(status), done = VALUES(done), assignee = VALUES(assignee),
+                self = VALUES(self), deleted = VALUES(deleted), updated_at = VALUES(updated_at)`,
+			e.issue.ID, e.issue.Key, fields.Summary, issueType, status, issueDone(fields),
+			userName(fields.Assignee), e.issue.Self, e.event == rules.EventIssueDeleted, now,
+		)
+		if err != nil {
+			return fmt.Errorf("写入问题 %s 快照失败: %v", e.issue.Key, err)
+		}
+
+		if err := saveIssueSprints(db, e, now); err != nil {
+			return err
+		}
+		return saveIssueVersions(db, e)
+	})
+}
+
+// saveIssueSprints 记录问题当前所在的 Sprint，以及变更记录中被移出的 Sprint
+// 加入时间只取自变更记录，首次见到时已在 Sprint 中的问题不知道加入时间，记为 NULL
+// 删除的问题移出全部 Sprint
+func saveIssueSprints(db *sql.DB, e issueEvent, now time.Time) error {
+	var added, removed []int
+	for _, item := range e.items() {
+		if strings.EqualFold(item.Field, "Sprint") {
+			a, r := sprintChanges(item)
+			added, removed = append(added, a...), append(removed, r...)
+		}
+	}
+
+	for _, s := range e.fields().Sprints {
+		var addedAt interface{}
+		if slices.Contains(added, s.ID) {
+			addedAt = now
+		}
+		if _, err := db.Exec(`
+            INSERT INTO jirahook_sprint_issue (sprint_id, issue_id, added_at) VALUES (?, ?, ?)
+            ON DUPLICATE KEY UPDATE
+                added_at = IF(VALUES(added_at) IS NULL, added_at, VALUES(added_at)),
+                removed_at = NULL`,
+			s.ID, e.issue.ID, addedAt); err != nil {
+			return fmt.Errorf("写入问题 %s 的 Sprint 失败: %v", e.issue.Key, err)
+		}
+	}
+
+	query := `UPDATE jirahook_sprint_issue SET removed_at = ? WHERE issue_id = ? AND removed_at IS NULL`
+	args := []interface{}{now, e.issue.ID}
+	if e.event != rules.EventIssueDeleted {
+		if len(removed) == 0 {
+			return nil
+		}
+		query += ` AND sprint_id IN (?` + strings.Repeat(`, ?`, len(removed)-1) + `)`
+		for _, id := range removed {
+			args = append(args, id)
+		}
+	}
+	if _, err := db.Exec(query, args...); err != nil {
+		return fmt.Errorf("更新问题 %s 的 Sprint 失败: %v", e.issue.Key, err)
+	}
+	return nil
+}
+
+// saveIssueVersions 以问题当前的修复版本替换已记录的版本
+func saveIssueVersions(db *sql.DB, e issueEvent) error {
+	fields := e.fields()
+	var projectKey string
+	if fields.Project != nil {
+		projectKey = fields.Project.Key
+	}
+
+	if _, err := db.Exec(`DELETE FROM jirahook_issue_version WHERE issue_id = ?`, e.issue.ID); err != nil {
+		return fmt.Errorf("更新问题 %s 的修复版本失败: %v", e.issue.Key, err)
+	}
+	for _, v := range fields.FixVersions {
+		if v == nil || v.ID == "" {
+			continue
+		}
+		if _, err := db.Exec(`INSERT INTO jirahook_issue_version (version_id, issue_id, project_key) VALUES (?, ?, ?)`,
+			v.ID, e.issue.ID, projectKey); err != nil {
+			return fmt.Errorf("写入问题 %s 的修复版本失败: %v", e.issue.Key, err)
+		}
+		if err := saveVersion(db, (*objects.Version)(v)); err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+// saveVersion 记录版本信息
+func saveVersion(db *sql.DB, v *objects.Version) error {
+	_, err := db.Exec(`
+        INSERT INTO jirahook_version (version_id, name, description, project_id, released, release_date)
+        VALUES (?, ?, ?, ?, ?, ?)
+        ON DUPLICATE KEY UPDATE
+            name = VALUES(name), description = VALUES(description), released = VALUES(released),
+            project_id = IF(VALUES(project_id) = 0, project_id, VALUES(project_id)),
+            release_date = IF(VALUES(release_date) = '', release_date, VALUES(release_date))`,
+		v.ID, v.Name, v.Description, v.ProjectID, v.Released, v.ReleaseDate)
+	if err != nil {
+		return fmt.Errorf("写入版本 %s 失败: %v", v.Name, err)
+	}
+	return nil
+}
+
+// issueDone 判断问题是否已完成
+func issueDone(fields *objects.IssueFields) bool {
+	if fields.Status != nil && fields.Status.StatusCategory.Key == "done" {
+		return true
+	}
+	return fields.Resolution != nil
+}
+
+// sprintChanges 返回 Sprint 变更记录中加入和移出的 Sprint ID，from 和 to 为逗号分隔的 ID
+func sprintChanges(item *objects.ChangeLogItem) (added, removed []int) {
+	from, to := sprintIDs(item.From), sprintIDs(item.To)
+	for _, id := range to {
+		if !slices.Contains(from, id) {
+			added = append(added, id)
+		}
+	}
+	for _, id := range from {
+		if !slices.Contains(to, id) {
+			removed = append(removed, id)
+		}
+	}
+	return added, removed
+}
+
+func sprintIDs(v interface{}) []int {
+	s, _ := v.(string)
+	var ids []int
+	for _, part := range strings.Split(s, ",") {
+		if id, err := strconv.Atoi(strings.TrimSpace(part)); err == nil {
+			ids = append(ids, id)
+		}
+	}
+	return ids
+}
@@ -0,0 +1,29 @@
+package handler
+
+import (
+	"reflect"
+	"testing"
+	"whenchangesth/internal/objects"
+)
+
+func TestSprintChanges(t *testing.T) {
+	cases := []struct {
+		name           string
+		from, to       interface{}
+		added, removed []int
+	}{
+		{"added to first sprint", "", "12", []int{12}, nil},
+		{"moved to next sprint", "12", "13", []int{13}, []int{12}},
+		{"carried over keeps old sprint", "12", "12, 13", []int{13}, nil},
+		{"removed", "12,13", "13", nil, []int{12}},
+		{"missing values", nil, nil, nil, nil},
+	}
+	for _, tc := range cases {
+		t.Run(tc.name, func(t *testing.T) {
+			added, removed := sprintChanges(&objects.ChangeLogItem{Field: "Sprint", From: tc.from, To: tc.to})
+			if !reflect.DeepEqual(added, tc.added) || !reflect.DeepEqual(removed, tc.removed) {
+				t.Errorf("sprintChanges() = %v, %v, want %v, %v", added, removed, tc.added, tc.removed)
+			}
+		})
+	}
+}
@@ -0,0 +1,70 @@
+package handler
+
+import (
+	"database/sql"
+	"fmt"
+	"whenchangesth/internal/objects"
+	"whenchangesth/internal/report"
+	"whenchangesth/pkg"
+)
+
+// handleSprint 处理 Sprint 开始和关闭事件
+// 开始时列出承诺的问题，关闭时发送完成情况报告，问题来自此前记录的问题快照
+func handleSprint(payload interface{}) error {
+	var sprint *objects.Sprint
+	var render func(*objects.Sprint, []report.SprintIssue) string
+	switch pl := payload.(type) {
+	case pkg.SprintStartedPayload:
+		sprint, render = pl.Sprint, report.SprintStartMarkdown
+	case pkg.SprintClosedPayload:
+		sprint, render = pl.Sprint, report.SprintCloseMarkdown
+	default:
+		return fmt.Errorf("invalid payload type for sprint: %T", payload)
+	}
+	if sprint == nil {
+		return nil
+	}
+
+	issues, err := loadSprintIssues(sprint.ID)
+	if err != nil {
+		return err
+	}
+	content := render(sprint, issues)
+	for _, robot := range appCfg.SprintRobots(sprint.OriginBoardID) {
+		deliverNotification(robot, content, nil)
+	}
+	return nil
+}
+
+// loadSprintIssues 读取当前仍在 Sprint 中的问题
+func loadSprintIssues(sprintID int) ([]report.SprintIssue, error) {
+	var issues []report.SprintIssue
+	err := withDB(func(db *sql.DB) error {
+		rows, err := db.Query(`
+            SELECT i.issue_key, i.summary, i.self, i.assignee, i.status, i.done, s.added_at
+            FROM jirahook_sprint_issue s JOIN jirahook_issue i ON i.issue_id = s.issue_id
+            WHERE s.sprint_id = ? AND s.removed_at IS NULL AND i.deleted = 0
+            ORDER BY i.issue_key`, sprintID)
+		if err != nil {
+			return err
+		}
+		defer rows.Close()
+
+		for rows.Next() {
+			var i report.SprintIssue
+			var self string
+			var addedAt sql.NullTime
+			if err := rows.Scan(&i.Key, &i.Summary, &self, &i.Assignee, &i.Status, &i.Done, &addedAt); err != nil {
+				return err
+			}
+			i.AddedAt = addedAt.Time
+			i.Link = issueLink(i.Key, self)
+			issues = append(issues, i)
+		}
+		return rows.Err()
+	})
+	if err != nil {
+		return nil, fmt.Errorf("读取 Sprint %d 的问题失败: %v", sprintID, err)
+	}
+	return issues, nil
+}
@@ -0,0 +1,171 @@
+package handler
+
+import (
+	"context"
+	"database/sql"
+	"fmt"
+	"log"
+	"slices"
+	"sort"
+	"time"
+	"whenchangesth/internal/conf"
+	"whenchangesth/internal/timesheet"
+)
+
+// 工时日报按团队和日期记录发送实例，保留两天
+const (
+	timesheetSentPrefix = "timesheet_sent:"
+	timesheetSentTTL    = 48 * time.Hour
+)
+
+// claimTimesheet 认领某个团队某天的工时日报，已被其他实例认领时返回 false
+// Redis 不可用时返回 true，宁可重复发送也不漏发
+func claimTimesheet(team string, date time.Time) bool {
+	key := timesheetSentPrefix + team + ":" + date.Format(time.DateOnly)
+	first, err := RedisClient.SetNX(ctx, key, 1, timesheetSentTTL).Result()
+	if err != nil {
+		fmt.Printf("⚠️ 认领工时日报 %s 失败，继续发送: %v\n", key, err)
+		return true
+	}
+	return first
+}
+
+// nextTimesheetRun 返回 now 之后下一次发送工时日报的时间
+func nextTimesheetRun(now time.Time, at string) (time.Time, error) {
+	t, err := time.Parse("15:04", at)
+	if err != nil {
+		return time.Time{}, err
+	}
+	next := time.Date(now.Year(), now.Month(), now.Day(), t.Hour(), t.Minute(), 0, 0, now.Location())
+	if !next.After(now) {
+		next = next.AddDate(0, 0, 1)
+	}
+	return next, nil
+}
+
+// runTimesheet 每天在配置的时间发送当天的工时日报，ctx 结束时退出
+func runTimesheet(ctx context.Context, cfg conf.TimesheetConfig) {
+	for {
+		next, err := nextTimesheetRun(time.Now(), cfg.At)
+		if err != nil {
+			log.Printf("⚠️ 工时日报时间无效: %v", err)
+			return
+		}
+		timer := time.NewTimer(time.Until(next))
+		select {
+		case <-ctx.Done():
+			timer.Stop()
+			return
+		case <-timer.C:
+		}
+		if err := sendTimesheets(cfg, next); err != nil {
+			log.Printf("⚠️ 工时日报发送失败: %v", err)
+		}
+	}
+}
+
+// sendTimesheets 汇总 date 当天的工时，按团队发送到对应机器人
+func sendTimesheets(cfg conf.TimesheetConfig, date time.Time) error {
+	entries, err := loadWorklogEntries(date)
+	if err != nil {
+		return err
+	}
+
+	people := phoneBook.People()
+	teamOf := make(map[string]string, len(people))
+	for _, p := range people {
+		teamOf[p.Name] = p.Team
+	}
+
+	teams := make([]string, 0, len(cfg.Teams))
+	for team := range cfg.Teams {
+		teams = append(teams, team)
+	}
+	sort.Strings(teams)
+
+	limits := timesheet.Limits{MaxDaily: cfg.MaxDaily, BackdateLimit: cfg.BackdateLimit}
+	for _, team := range teams {
+		var members []string
+		for _, p := range people {
+			if p.Team == team {
+				members = append(members, p.Name)
+			}
+		}
+		var teamEntries []timesheet.Entry
+		for _, e := range entries {
+			if teamOf[e.Person] == team {
+				teamEntries = append(teamEntries, e)
+			}
+		}
+
+		report := timesheet.Build(team, date, members, teamEntries, limits)
+		// 提醒未登记工时的人
+		var mentions []mention
+		for _, p := range people {
+			if p.Team == team && slices.Contains(report.Missing, p.Name) {
+				mentions = appendMentions(mentions, mention{Mobile: p.Phone, UserID: p.DingUserID})
+			}
+		}
+		for _, m := range mentions {
+			if m.UserID != "" {
+				report.Mentions = append(report.Mentions, m.UserID)
+			} else if m.Mobile != "" {
+				report.Mentions = append(report.Mentions, m.Mobile)
+			}
+		}
+
+		// 多实例部署时每个团队每天只由一个实例发送
+		if !claimTimesheet(team, date) {
+			continue
+		}
+		deliverNotification(cfg.Teams[team], report.Markdown(), mentions)
+	}
+	return nil
+}
+
+// loadWorklogEntries 读取开始时间或登记时间在 date 当天的工时，并按通讯录归属到人
+// 通讯录中找不到的作者不参与汇总
+func loadWorklogEntries(date time.Time) ([]timesheet.Entry, error) {
+	start, end := timesheet.DayRange(date)
+
+	var entries []timesheet.Entry
+	err := withDB(func(db *sql.DB) error {
+		rows, err := db.Query(`
+            SELECT worklog_id, issue_id, issue_key, issue_summary, author_account_id, author_key, author_name,
+            author_email, author_display_name, started, time_spent_seconds, created
+            FROM jirahook_worklog
+            WHERE (started >= ? AND started < ?) OR (created >= ? AND created < ?)`,
+			start, end, start, end)
+		if err != nil {
+			return err
+		}
+		defer rows.Close()
+
+		for rows.Next() {
+			var e timesheet.Entry
+			var issueID string
+			var ref conf.UserRef
+			var created sql.NullTime
+			if err := rows.Scan(&e.WorklogID, &issueID, &e.IssueKey, &e.Summary,
+				&ref.AccountID, &ref.Key, &ref.Name, &ref.Email, &ref.DisplayName,
+				&e.Started, &e.Seconds, &created); err != nil {
+				return err
+			}
+			p, ok := phoneBook.Resolve(ref)
+			if !ok {
+				continue
+			}
+			e.Person = p.Name
+			e.Created = created.Time
+			if e.IssueKey == "" {
+				e.IssueKey = "#" + issueID
+			}
+			entries = append(entries, e)
+		}
+		return rows.Err()
+	})
+	if err != nil {
+		return nil, fmt.Errorf("读取工时失败: %v", err)
+	}
+	return entries, nil
+}
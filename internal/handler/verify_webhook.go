@@ -0,0 +1,139 @@
+package handler
+
+import (
+	"bytes"
+	"crypto/hmac"
+	"crypto/sha256"
+	"crypto/subtle"
+	"encoding/hex"
+	"encoding/json"
+	"io"
+	"log"
+	"net/http"
+	"strings"
+	"sync"
+	"time"
+	"whenchangesth/internal/conf"
+	"whenchangesth/internal/metrics"
+
+	"github.com/gin-gonic/gin"
+)
+
+// 校验失败原因
+const (
+	verifyBadBody      = "bad_body"
+	verifyBadSignature = "bad_signature"
+	verifyBadToken     = "bad_token"
+	verifyReplay       = "replay"
+)
+
+// signatureHeader 是 Jira Cloud 携带 HMAC 签名的请求头
+const signatureHeader = "X-Hub-Signature"
+
+// verifyFailures 按原因统计校验失败次数
+var (
+	verifyFailures   = make(map[string]uint64)
+	verifyFailuresMu sync.Mutex
+)
+
+// VerifyWebhook 校验 webhook 来源，校验失败时返回 401
+func VerifyWebhook(cfg conf.WebhookConfig) gin.HandlerFunc {
+	return func(c *gin.Context) {
+		body, err := io.ReadAll(c.Request.Body)
+		if err != nil {
+			rejectWebhook(c, verifyBadBody, err.Error())
+			return
+		}
+		// 将 body 写回，供后续解析使用
+		c.Request.Body = io.NopCloser(bytes.NewBuffer(body))
+
+		if reason, detail := verifyWebhook(cfg, c.Request, body, time.Now()); reason != "" {
+			rejectWebhook(c, reason, detail)
+			return
+		}
+		c.Next()
+	}
+}
+
+// verifyWebhook 依次进行签名、共享密钥和重放窗口校验，通过时返回空原因
+func verifyWebhook(cfg conf.WebhookConfig, r *http.Request, body []byte, now time.Time) (reason, detail string) {
+	if cfg.Secret != "" && !validSignature(cfg.Secret, r.Header.Get(signatureHeader), body) {
+		return verifyBadSignature, "签名不匹配"
+	}
+
+	if cfg.Token != "" {
+		token := ""
+		if cfg.TokenHeader != "" {
+			token = r.Header.Get(cfg.TokenHeader)
+		}
+		if token == "" && cfg.TokenParam != "" {
+			token = r.URL.Query().Get(cfg.TokenParam)
+		}
+		if subtle.ConstantTimeCompare([]byte(token), []byte(cfg.Token)) != 1 {
+			return verifyBadToken, "token 不匹配"
+		}
+	}
+
+	if cfg.ReplayWindow > 0 {
+		var ts struct {
+			Timestamp int64 `json:"timestamp"`
+		}
+		if err := json.Unmarshal(body, &ts); err != nil || ts.Timestamp == 0 {
+			return verifyReplay, "缺少 timestamp"
+		}
+		sent := payloadTime(ts.Timestamp)
+		if skew := now.Sub(sent); skew > cfg.ReplayWindow || skew < -cfg.ReplayWindow {
+			return verifyReplay, "timestamp 超出重放窗口: " + sent.Format(time.RFC3339)
+		}
+	}
+
+	return "", ""
+}
+
+// validSignature 校验形如 sha256=<hex> 的 HMAC 签名
+func validSignature(secret, header string, body []byte) bool {
+	sig, ok := strings.CutPrefix(header, "sha256=")
+	if !ok {
+		return false
+	}
+	got, err := hex.DecodeString(sig)
+	if err != nil {
+		return false
+	}
+	mac := hmac.New(sha256.New, []byte(secret))
+	mac.Write(body)
+	return hmac.Equal(got, mac.Sum(nil))
+}
+
+// payloadTime 将 Jira 的 timestamp 转为时间，兼容毫秒和秒两种精度
+func payloadTime(ts int64) time.Time {
+	if ts > 1e12 {
+		return time.UnixMilli(ts)
+	}
+	return time.Unix(ts, 0)
+}
+
+func rejectWebhook(c *gin.Context, reason, detail string) {
+	verifyFailuresMu.Lock()
+	verifyFailures[reason]++
+	total := verifyFailures[reason]
+	verifyFailuresMu.Unlock()
+	metrics.WebhookVerifyFailures.WithLabelValues(reason).Inc()
+
+	log.Printf("⚠️ webhook 校验失败 ip=%s reason=%s(%d) %s", c.ClientIP(), reason, total, detail)
+	c.AbortWithStatusJSON(http.StatusUnauthorized, gin.H{
+		"error": "Unauthorized webhook request",
+	})
+}
+
+// WebhookStatusHandler 返回各原因的 webhook 校验失败次数
+func WebhookStatusHandler(c *gin.Context) {
+	verifyFailuresMu.Lock()
+	failures := make(map[string]uint64, len(verifyFailures))
+	for k, v := range verifyFailures {
+		failures[k] = v
+	}
+	verifyFailuresMu.Unlock()
+
+	c.JSON(http.StatusOK, gin.H{"verify_failures": failures})
+}
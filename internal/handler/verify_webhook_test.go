@@ -0,0 +1,59 @@
+package handler
+
+import (
+	"crypto/hmac"
+	"crypto/sha256"
+	"encoding/hex"
+	"net/http/httptest"
+	"testing"
+	"time"
+	"whenchangesth/internal/conf"
+)
+
+func TestVerifyWebhook(t *testing.T) {
+	now := time.UnixMilli(1700000000000)
+	body := []byte(`{"timestamp":1700000000000,"webhookEvent":"jira:issue_created"}`)
+	mac := hmac.New(sha256.New, []byte("s3cret"))
+	mac.Write(body)
+	goodSig := "sha256=" + hex.EncodeToString(mac.Sum(nil))
+
+	cfg := conf.WebhookConfig{
+		Secret:       "s3cret",
+		Token:        "t0ken",
+		TokenParam:   "token",
+		TokenHeader:  "X-Jirahook-Token",
+		ReplayWindow: 5 * time.Minute,
+	}
+
+	cases := []struct {
+		name   string
+		url    string
+		sig    string
+		now    time.Time
+		reason string
+	}{
+		{"ok", "/jira/webhook?token=t0ken", goodSig, now, ""},
+		{"bad signature", "/jira/webhook?token=t0ken", "sha256=00", now, verifyBadSignature},
+		{"missing signature", "/jira/webhook?token=t0ken", "", now, verifyBadSignature},
+		{"bad token", "/jira/webhook?token=nope", goodSig, now, verifyBadToken},
+		{"replayed", "/jira/webhook?token=t0ken", goodSig, now.Add(time.Hour), verifyReplay},
+	}
+	for _, c := range cases {
+		r := httptest.NewRequest("POST", c.url, nil)
+		if c.sig != "" {
+			r.Header.Set(signatureHeader, c.sig)
+		}
+		if reason, _ := verifyWebhook(cfg, r, body, c.now); reason != c.reason {
+			t.Errorf("%s: reason = %q, want %q", c.name, reason, c.reason)
+		}
+	}
+}
+
+func TestVerifyWebhookTokenHeader(t *testing.T) {
+	cfg := conf.WebhookConfig{Token: "t0ken", TokenHeader: "X-Jirahook-Token"}
+	r := httptest.NewRequest("POST", "/jira/webhook", nil)
+	r.Header.Set("X-Jirahook-Token", "t0ken")
+	if reason, _ := verifyWebhook(cfg, r, []byte(`{}`), time.Now()); reason != "" {
+		t.Fatalf("reason = %q", reason)
+	}
+}
@@ -0,0 +1,143 @@
+package handler
+
+import (
+	"database/sql"
+	"fmt"
+	"strings"
+	"time"
+	"whenchangesth/internal/objects"
+	"whenchangesth/pkg"
+)
+
+// handleWorkLog 处理独立的工时创建、更新和删除事件
+// 这类事件只带有 issueId，问题 key 和摘要由问题事件补全
+func handleWorkLog(payload interface{}) error {
+	switch pl := payload.(type) {
+	case pkg.WorkLogCreatedPayload:
+		return saveWorklogs(nil, pl.WorkLog)
+	case pkg.WorkLogUpdatedPayload:
+		return saveWorklogs(nil, pl.WorkLog)
+	case pkg.WorkLogDeletedPayload:
+		return deleteWorklog(pl.WorkLog)
+	default:
+		return fmt.Errorf("invalid payload type for worklog: %T", payload)
+	}
+}
+
+// handleIssueWorkLog 处理问题更新事件中的工时动作，按问题中携带的工时列表同步
+func handleIssueWorkLog(payload interface{}) error {
+	var issue *objects.Issue
+	switch pl := payload.(type) {
+	case pkg.IssueWorkLogCreatedPayload:
+		issue = pl.Issue
+	case pkg.IssueWorkLogUpdatedPayload:
+		issue = pl.Issue
+	case pkg.IssueWorkLogDeletedPayload:
+		issue = pl.Issue
+	default:
+		return fmt.Errorf("invalid payload type for issue worklog: %T", payload)
+	}
+	if issue == nil || issue.Fields == nil || issue.Fields.Worklog == nil {
+		return nil
+	}
+
+	wl := issue.Fields.Worklog
+	if err := saveWorklogs(issue, wl.WorkLogs...); err != nil {
+		return err
+	}
+	// 问题中携带的是完整列表时，清理已在 Jira 中删除的记录
+	if wl.Total == len(wl.WorkLogs) {
+		return pruneWorklogs(issue.ID, wl.WorkLogs)
+	}
+	return nil
+}
+
+// saveWorklogs 写入或更新工时记录，issue 为空时保留已有的问题 key 和摘要
+func saveWorklogs(issue *objects.Issue, records ...*objects.WorkLogRecord) error {
+	return writeDB("jirahook_worklog", func(db *sql.DB) error {
+
+		var issueID, issueKey, summary string
+		if issue != nil {
+			issueID = issue.ID
+			issueKey = issue.Key
+			if issue.Fields != nil {
+				summary = issue.Fields.Summary
+			}
+		}
+		for _, r := range records {
+			if r == nil || r.ID == "" {
+				continue
+			}
+			author := r.Author
+			if author == nil {
+				author = &objects.User{}
+			}
+			rowIssueID := r.IssueID
+			if rowIssueID == "" {
+				rowIssueID = issueID
+			}
+			_, err := db.Exec(`
+            INSERT INTO jirahook_worklog
+            (worklog_id, issue_id, issue_key, issue_summary, author_account_id, author_key, author_name,
+            author_email, author_display_name, started, time_spent_seconds, created, updated)
+            VALUES (?, ?, ?, ?, ?, ?, ?, ?, ?, ?, ?, ?, ?)
+            ON DUPLICATE KEY UPDATE
+                issue_key = IF(VALUES(issue_key) = '', issue_key, VALUES(issue_key)),
+                issue_summary = IF(VALUES(issue_key) = '', issue_summary, VALUES(issue_summary)),
+                started = VALUES(started),
+                time_spent_seconds = VALUES(time_spent_seconds),
+                updated = VALUES(updated)`,
+				r.ID, rowIssueID, issueKey, summary,
+				author.AccountID, author.Key, author.Name, author.EmailAddress, author.DisplayName,
+				localTime(r.Started), r.TimeSpentSeconds, nullTime(r.Created), nullTime(r.Updated),
+			)
+			if err != nil {
+				return fmt.Errorf("写入工时 %s 失败: %v", r.ID, err)
+			}
+		}
+		return nil
+	})
+}
+
+// deleteWorklog 删除一条工时记录
+func deleteWorklog(r *objects.WorkLogRecord) error {
+	if r == nil || r.ID == "" {
+		return nil
+	}
+	return writeDB("jirahook_worklog", func(db *sql.DB) error {
+		_, err := db.Exec(`DELETE FROM jirahook_worklog WHERE worklog_id = ?`, r.ID)
+		return err
+	})
+}
+
+// pruneWorklogs 删除某个问题下不在 keep 中的工时记录
+func pruneWorklogs(issueID string, keep []*objects.WorkLogRecord) error {
+	if issueID == "" {
+		return nil
+	}
+	query := `DELETE FROM jirahook_worklog WHERE issue_id = ?`
+	args := []interface{}{issueID}
+	if len(keep) > 0 {
+		query += ` AND worklog_id NOT IN (?` + strings.Repeat(`, ?`, len(keep)-1) + `)`
+		for _, r := range keep {
+			args = append(args, r.ID)
+		}
+	}
+	return writeDB("jirahook_worklog", func(db *sql.DB) error {
+		_, err := db.Exec(query, args...)
+		return err
+	})
+}
+
+// localTime 将 Jira 时间转换为本地时间写入 DATETIME 字段
+func localTime(t objects.Time) time.Time {
+	return time.Time(t).In(time.Local)
+}
+
+// nullTime 将零值时间写为 NULL
+func nullTime(t objects.Time) interface{} {
+	if time.Time(t).IsZero() {
+		return nil
+	}
+	return localTime(t)
+}
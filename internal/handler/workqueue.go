@@ -0,0 +1,92 @@
+package handler
+
+import (
+	"log"
+	"sync"
+	"time"
+	"whenchangesth/internal/metrics"
+	"whenchangesth/pkg"
+)
+
+// webhookJob 是一条已解析、等待处理的 webhook
+type webhookJob struct {
+	event    pkg.Event
+	payload  interface{}
+	received time.Time
+	archive  *archivedWebhook // 开启存档时保存原始请求
+}
+
+// workPool 是有界的 webhook 处理队列，由固定数量的 worker 处理
+type workPool struct {
+	jobs    chan webhookJob
+	handle  func(webhookJob)
+	workers int
+
+	mu     sync.RWMutex
+	closed bool
+}
+
+// webhookPool 在 Init 中创建
+var webhookPool *workPool
+
+// newWorkPool 创建容量为 size 的队列，调用 start 后开始处理
+func newWorkPool(size, workers int, handle func(webhookJob)) *workPool {
+	return &workPool{jobs: make(chan webhookJob, size), handle: handle, workers: workers}
+}
+
+// start 启动 worker，worker 在队列关闭且处理完剩余任务后退出
+func (p *workPool) start() {
+	for i := 0; i < p.workers; i++ {
+		goBackground(func() {
+			for job := range p.jobs {
+				p.handle(job)
+			}
+		})
+	}
+}
+
+// submit 将任务放入队列，队列已满或已关闭时返回 false
+func (p *workPool) submit(job webhookJob) bool {
+	p.mu.RLock()
+	defer p.mu.RUnlock()
+	if p.closed {
+		return false
+	}
+	select {
+	case p.jobs <- job:
+		return true
+	default:
+		return false
+	}
+}
+
+// close 停止接收任务，已在队列中的任务继续处理
+func (p *workPool) close() {
+	p.mu.Lock()
+	defer p.mu.Unlock()
+	if !p.closed {
+		p.closed = true
+		close(p.jobs)
+	}
+}
+
+// depth 返回队列中等待处理的任务数和队列容量
+func (p *workPool) depth() (queued, capacity int) {
+	return len(p.jobs), cap(p.jobs)
+}
+
+// processWebhook 存档原始请求，并调用事件对应的处理器
+func processWebhook(job webhookJob) {
+	metrics.WebhookQueueWait.Observe(time.Since(job.received).Seconds())
+	if job.archive != nil {
+		archiveWebhook(job.archive)
+	}
+	handlerFunc, ok := eventHandlers[job.event]
+	if !ok {
+		return
+	}
+	if err := handlerFunc(job.payload); err != nil {
+		log.Printf("Error processing event %s: %v", job.event, err)
+		metrics.HandlerErrors.WithLabelValues(string(job.event)).Inc()
+	}
+}
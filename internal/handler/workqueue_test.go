@@ -0,0 +1,58 @@
+package handler
+
+import (
+	"sync"
+	"testing"
+	"time"
+)
+
+func TestWorkPoolRejectsWhenFull(t *testing.T) {
+	release := make(chan struct{})
+	started := make(chan struct{}, 1)
+	var mu sync.Mutex
+	var handled []string
+	p := newWorkPool(1, 1, func(job webhookJob) {
+		started <- struct{}{}
+		<-release
+		mu.Lock()
+		handled = append(handled, string(job.event))
+		mu.Unlock()
+	})
+	p.start()
+
+	if !p.submit(webhookJob{event: "a"}) {
+		t.Fatal("first submit rejected")
+	}
+	<-started // worker 取走第一个任务后阻塞
+	if !p.submit(webhookJob{event: "b"}) {
+		t.Fatal("second submit rejected while queue has room")
+	}
+	if p.submit(webhookJob{event: "c"}) {
+		t.Error("submit accepted while queue is full")
+	}
+	if queued, capacity := p.depth(); queued != 1 || capacity != 1 {
+		t.Errorf("depth = %d/%d, want 1/1", queued, capacity)
+	}
+
+	// 关闭后不再接收任务，已在队列中的任务继续处理
+	p.close()
+	p.close()
+	if p.submit(webhookJob{event: "d"}) {
+		t.Error("submit accepted after close")
+	}
+	close(release)
+	<-started
+
+	done := make(chan struct{})
+	go func() { background.Wait(); close(done) }()
+	select {
+	case <-done:
+	case <-time.After(time.Second):
+		t.Fatal("workers did not exit after close")
+	}
+	mu.Lock()
+	defer mu.Unlock()
+	if len(handled) != 2 || handled[0] != "a" || handled[1] != "b" {
+		t.Errorf("handled = %v, want [a b]", handled)
+	}
+}
@@ -0,0 +1,152 @@
+package metrics
+
+import (
+	"errors"
+	"strconv"
+	"whenchangesth/internal/ding"
+	"whenchangesth/pkg"
+
+	"github.com/prometheus/client_golang/prometheus"
+	"github.com/prometheus/client_golang/prometheus/promauto"
+)
+
+// namespace 是全部指标名称的前缀
+const namespace = "jirahook"
+
+var (
+	// WebhooksReceived 按 webhookEvent 和 issue_event_type_name 统计收到的 webhook
+	WebhooksReceived = promauto.NewCounterVec(prometheus.CounterOpts{
+		Namespace: namespace,
+		Name:      "webhooks_received_total",
+		Help:      "Jira webhooks received, by event and action.",
+	}, []string{"event", "action"})
+
+	// WebhookParseFailures 按原因统计解析失败的 webhook
+	WebhookParseFailures = promauto.NewCounterVec(prometheus.CounterOpts{
+		Namespace: namespace,
+		Name:      "webhook_parse_failures_total",
+		Help:      "Jira webhooks that could not be parsed, by reason.",
+	}, []string{"reason"})
+
+	// WebhookVerifyFailures 按原因统计来源校验失败的 webhook
+	WebhookVerifyFailures = promauto.NewCounterVec(prometheus.CounterOpts{
+		Namespace: namespace,
+		Name:      "webhook_verify_failures_total",
+		Help:      "Jira webhooks rejected by source verification, by reason.",
+	}, []string{"reason"})
+
+	// HandlerErrors 按事件类型统计处理器返回的错误
+	HandlerErrors = promauto.NewCounterVec(prometheus.CounterOpts{
+		Namespace: namespace,
+		Name:      "handler_errors_total",
+		Help:      "Errors returned by event handlers, by event.",
+	}, []string{"event"})
+
+	// WebhookDuplicates 统计重复投递被跳过的 webhook
+	WebhookDuplicates = promauto.NewCounter(prometheus.CounterOpts{
+		Namespace: namespace,
+		Name:      "webhook_duplicates_total",
+		Help:      "Redelivered webhooks skipped because their idempotency key was already seen.",
+	})
+
+	// WebhookQueueRejected 统计处理队列已满时拒绝的 webhook
+	WebhookQueueRejected = promauto.NewCounter(prometheus.CounterOpts{
+		Namespace: namespace,
+		Name:      "webhook_queue_rejected_total",
+		Help:      "Webhooks rejected with 503 because the processing queue was full.",
+	})
+
+	// WebhookQueueWait 记录 webhook 在处理队列中的等待时间
+	WebhookQueueWait = promauto.NewHistogram(prometheus.HistogramOpts{
+		Namespace: namespace,
+		Name:      "webhook_queue_wait_seconds",
+		Help:      "Time webhooks waited in the processing queue before a worker picked them up.",
+		Buckets:   []float64{0.001, 0.01, 0.1, 0.5, 1, 5, 10, 30},
+	})
+
+	// DebounceBucketSize 记录每次发送的去抖桶中的事件数
+	DebounceBucketSize = promauto.NewHistogram(prometheus.HistogramOpts{
+		Namespace: namespace,
+		Name:      "debounce_bucket_events",
+		Help:      "Number of events in a debounce bucket when it is flushed.",
+		Buckets:   []float64{1, 2, 5, 10, 20, 50, 100, 200, 500},
+	})
+
+	// TemplateRenderFailures 按机器人和事件类型统计自定义模板运行时渲染失败、改用内置模板的次数
+	TemplateRenderFailures = promauto.NewCounterVec(prometheus.CounterOpts{
+		Namespace: namespace,
+		Name:      "template_render_failures_total",
+		Help:      "Custom template render failures that fell back to the builtin templates, by robot and event type.",
+	}, []string{"robot", "event_type"})
+
+	// DingTalkSendDuration 记录钉钉接口的调用耗时
+	DingTalkSendDuration = promauto.NewHistogramVec(prometheus.HistogramOpts{
+		Namespace: namespace,
+		Name:      "dingtalk_send_duration_seconds",
+		Help:      "Latency of DingTalk robot API calls, by robot.",
+		Buckets:   prometheus.DefBuckets,
+	}, []string{"robot"})
+
+	// DingTalkSends 按机器人和 errcode 统计钉钉接口调用，成功时 errcode 为 0
+	// 网络错误和非 200 响应的 errcode 为 transport
+	DingTalkSends = promauto.NewCounterVec(prometheus.CounterOpts{
+		Namespace: namespace,
+		Name:      "dingtalk_sends_total",
+		Help:      "DingTalk robot API calls, by robot and errcode (0 on success, transport for network or HTTP errors).",
+	}, []string{"robot", "errcode"})
+
+	// DingTalkQueueDelay 记录消息在限流队列中的等待时间
+	DingTalkQueueDelay = promauto.NewHistogramVec(prometheus.HistogramOpts{
+		Namespace: namespace,
+		Name:      "dingtalk_queue_delay_seconds",
+		Help:      "Time messages waited in the per-robot rate limit queue.",
+		Buckets:   []float64{0.01, 0.1, 0.5, 1, 2, 5, 10, 30, 60, 120},
+	}, []string{"robot"})
+
+	// DingTalkDeadLetters 统计重试次数用尽转入死信表的消息
+	DingTalkDeadLetters = promauto.NewCounterVec(prometheus.CounterOpts{
+		Namespace: namespace,
+		Name:      "dingtalk_dead_letters_total",
+		Help:      "Messages moved to the dead-letter table after exhausting retries, by robot.",
+	}, []string{"robot"})
+
+	// MySQLWriteFailures 按表统计写入失败
+	MySQLWriteFailures = promauto.NewCounterVec(prometheus.CounterOpts{
+		Namespace: namespace,
+		Name:      "mysql_write_failures_total",
+		Help:      "Failed MySQL writes, by table.",
+	}, []string{"table"})
+
+	// RedisErrors 按命令统计 Redis 错误，不包括 key 不存在
+	RedisErrors = promauto.NewCounterVec(prometheus.CounterOpts{
+		Namespace: namespace,
+		Name:      "redis_errors_total",
+		Help:      "Failed Redis commands, by command.",
+	}, []string{"command"})
+)
+
+// ParseFailureReason 返回 webhook 解析错误对应的 reason 标签
+func ParseFailureReason(err error) string {
+	switch {
+	case errors.Is(err, pkg.ErrEventNotFound):
+		return "event_not_found"
+	case errors.Is(err, pkg.ErrParsingPayload):
+		return "parsing_payload"
+	case errors.Is(err, pkg.ErrEventNotSpecifiedToParse):
+		return "event_not_specified"
+	default:
+		return "unknown_event"
+	}
+}
+
+// SendErrcode 返回钉钉发送结果对应的 errcode 标签
+func SendErrcode(err error) string {
+	if err == nil {
+		return "0"
+	}
+	var apiErr *ding.APIError
+	if errors.As(err, &apiErr) {
+		return strconv.Itoa(apiErr.Code)
+	}
+	return "transport"
+}
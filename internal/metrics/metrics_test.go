@@ -0,0 +1,34 @@
+package metrics
+
+import (
+	"errors"
+	"fmt"
+	"testing"
+	"whenchangesth/internal/ding"
+	"whenchangesth/pkg"
+)
+
+func TestParseFailureReason(t *testing.T) {
+	cases := map[error]string{
+		pkg.ErrEventNotFound:                             "event_not_found",
+		fmt.Errorf("wrapped: %w", pkg.ErrParsingPayload): "parsing_payload",
+		errors.New("unknown event 'x' with action: 'y'"): "unknown_event",
+	}
+	for err, want := range cases {
+		if got := ParseFailureReason(err); got != want {
+			t.Errorf("%v: got %q, want %q", err, got, want)
+		}
+	}
+}
+
+func TestSendErrcode(t *testing.T) {
+	if got := SendErrcode(nil); got != "0" {
+		t.Errorf("nil: got %q", got)
+	}
+	if got := SendErrcode(&ding.APIError{Code: 130101, Msg: "send too fast"}); got != "130101" {
+		t.Errorf("api error: got %q", got)
+	}
+	if got := SendErrcode(errors.New("failed to send request")); got != "transport" {
+		t.Errorf("transport error: got %q", got)
+	}
+}
@@ -1,5 +1,10 @@
 package objects
 
+import (
+	"encoding/json"
+	"strings"
+)
+
 type IssueType struct {
 	Self        string `json:"self"`
 	ID          string `json:"id"`
@@ -25,9 +30,10 @@ type Priority struct {
 }
 
 type Watches struct {
-	Self       string `json:"self"`
-	WatchCount int    `json:"watchCount"`
-	IsWatching bool   `json:"isWatching"`
+	Self       string  `json:"self"`
+	WatchCount int     `json:"watchCount"`
+	IsWatching bool    `json:"isWatching"`
+	Watchers   []*User `json:"watchers"`
 }
 
 type Component struct {
@@ -127,6 +133,30 @@ type IssueFields struct {
 	AggregateTimeOriginalEstimate int               `json:"aggregatetimeoriginalestimate"`
 	AggregateTimeSpent            int               `json:"aggregatetimespent"`
 	AggregateTimeEstimate         int               `json:"aggregatetimeestimate"`
+	// Sprints 取自 Jira 的 Sprint 自定义字段，字段 ID 因实例而异，解析时自动识别
+	Sprints []*Sprint `json:"-"`
+}
+
+func (f *IssueFields) UnmarshalJSON(b []byte) error {
+	type plain IssueFields
+	if err := json.Unmarshal(b, (*plain)(f)); err != nil {
+		return err
+	}
+
+	var raw map[string]json.RawMessage
+	if err := json.Unmarshal(b, &raw); err != nil {
+		return err
+	}
+	for key, value := range raw {
+		if !strings.HasPrefix(key, "customfield_") {
+			continue
+		}
+		if sprints, ok := parseSprintField(value); ok {
+			f.Sprints = sprints
+			break
+		}
+	}
+	return nil
 }
 
 type Issue struct {
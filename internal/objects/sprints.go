@@ -1,6 +1,10 @@
 package objects
 
 import (
+	"encoding/json"
+	"regexp"
+	"strconv"
+	"strings"
 	"time"
 )
 
@@ -15,3 +19,108 @@ type Sprint struct {
 	StartDate     *time.Time `json:"startDate"`
 	OldValue      *Sprint    `json:"oldValue"`
 }
+
+// sprintStringPrefix 是 Jira Server 中 Sprint 字段值的前缀
+const sprintStringPrefix = "com.atlassian.greenhopper.service.sprint.Sprint@"
+
+// parseSprintField 识别 Sprint 自定义字段，Jira Cloud 为对象数组，Jira Server 为
+// "com.atlassian.greenhopper.service.sprint.Sprint@xx[id=1,state=ACTIVE,name=...]" 形式的字符串数组
+func parseSprintField(value json.RawMessage) ([]*Sprint, bool) {
+	var items []json.RawMessage
+	if err := json.Unmarshal(value, &items); err != nil || len(items) == 0 {
+		return nil, false
+	}
+
+	var sprints []*Sprint
+	for _, item := range items {
+		var s string
+		if err := json.Unmarshal(item, &s); err == nil {
+			sprint, ok := parseSprintString(s)
+			if !ok {
+				return nil, false
+			}
+			sprints = append(sprints, sprint)
+			continue
+		}
+
+		var fields map[string]json.RawMessage
+		if err := json.Unmarshal(item, &fields); err != nil {
+			return nil, false
+		}
+		_, hasID := fields["id"]
+		_, hasState := fields["state"]
+		_, hasBoard := fields["boardId"]
+		if !hasID || !hasState || (!hasBoard && fields["originBoardId"] == nil) {
+			return nil, false
+		}
+		sprint := &Sprint{}
+		if err := json.Unmarshal(item, sprint); err != nil {
+			return nil, false
+		}
+		sprints = append(sprints, sprint)
+	}
+	return sprints, true
+}
+
+// parseSprintString 解析 Jira Server 的 Sprint 字段字符串
+func parseSprintString(s string) (*Sprint, bool) {
+	if !strings.HasPrefix(s, sprintStringPrefix) {
+		return nil, false
+	}
+	start := strings.Index(s, "[")
+	end := strings.LastIndex(s, "]")
+	if start < 0 || end < start {
+		return nil, false
+	}
+
+	sprint := &Sprint{}
+	// name 和 goal 中可能含有逗号，只在下一个 "key=" 之前切分
+	for _, kv := range splitSprintAttrs(s[start+1 : end]) {
+		key, value, _ := strings.Cut(kv, "=")
+		if value == "<null>" {
+			value = ""
+		}
+		switch key {
+		case "id":
+			id, err := strconv.Atoi(value)
+			if err != nil {
+				return nil, false
+			}
+			sprint.ID = id
+		case "rapidViewId":
+			sprint.OriginBoardID, _ = strconv.Atoi(value)
+		case "state":
+			sprint.State = strings.ToLower(value)
+		case "name":
+			sprint.Name = value
+		case "goal":
+			sprint.Goal = value
+		case "startDate":
+			sprint.StartDate = parseSprintTime(value)
+		case "endDate":
+			sprint.EndDate = parseSprintTime(value)
+		}
+	}
+	return sprint, sprint.ID != 0
+}
+
+var sprintAttrPattern = regexp.MustCompile(`,(\w+)=`)
+
+// splitSprintAttrs 按 ",key=" 切分属性
+func splitSprintAttrs(s string) []string {
+	var parts []string
+	last := 0
+	for _, loc := range sprintAttrPattern.FindAllStringIndex(s, -1) {
+		parts = append(parts, s[last:loc[0]])
+		last = loc[0] + 1
+	}
+	return append(parts, s[last:])
+}
+
+func parseSprintTime(value string) *time.Time {
+	t, err := time.Parse(time.RFC3339, value)
+	if err != nil {
+		return nil
+	}
+	return &t
+}
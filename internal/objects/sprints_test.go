@@ -0,0 +1,26 @@
+package objects
+
+import (
+	"encoding/json"
+	"testing"
+)
+
+func TestIssueFieldsSprints(t *testing.T) {
+	cases := map[string]string{
+		"cloud":  `{"summary":"s","customfield_10010":"x","customfield_10020":[{"id":12,"name":"Sprint 3, 后端","state":"active","boardId":5,"goal":"","startDate":"2026-10-01T08:00:00.000Z"}]}`,
+		"server": `{"summary":"s","customfield_10101":["com.atlassian.greenhopper.service.sprint.Sprint@1a2b[id=12,rapidViewId=5,state=ACTIVE,name=Sprint 3, 后端,goal=<null>,startDate=2026-10-01T16:00:00.000+08:00,endDate=<null>,completeDate=<null>,sequence=12]"]}`,
+	}
+	for name, data := range cases {
+		var f IssueFields
+		if err := json.Unmarshal([]byte(data), &f); err != nil {
+			t.Fatalf("%s: %v", name, err)
+		}
+		if f.Summary != "s" || len(f.Sprints) != 1 {
+			t.Fatalf("%s: fields = %+v", name, f)
+		}
+		s := f.Sprints[0]
+		if s.ID != 12 || s.Name != "Sprint 3, 后端" || s.State != "active" || s.StartDate == nil {
+			t.Errorf("%s: sprint = %+v", name, s)
+		}
+	}
+}
@@ -4,6 +4,7 @@ type User struct {
 	Self         string      `json:"self"`
 	Name         string      `json:"name"`
 	Key          string      `json:"key"`
+	AccountID    string      `json:"accountId"`
 	EmailAddress string      `json:"emailAddress"`
 	AvatarUrls   *AvatarUrls `json:"avatarUrls"`
 	DisplayName  string      `json:"displayName"`
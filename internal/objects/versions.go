@@ -11,6 +11,7 @@ type Version struct {
 	ProjectID       int    `json:"projectId"`
 	UserStartDate   string `json:"userStartDate"`
 	UserReleaseDate string `json:"userReleaseDate"`
+	ReleaseDate     string `json:"releaseDate"`
 }
 
 type FixVersion Version
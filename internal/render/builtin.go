@@ -0,0 +1,63 @@
+package render
+
+// genericEvent 是没有专用模板的事件类型使用的模板名
+const genericEvent = "generic"
+
+// builtinCommon 定义整体布局 message 和页脚 footer，事件模板需要定义 title 和 item
+const builtinCommon = `{{define "message"}}
+### **事件通知: {{template "title" .}}{{with .PartLabel}} ({{.}}){{end}}**             
+{{range .Items}}{{template "item" .}}{{end}}
+{{template "footer" .}}{{end}}
+{{- define "footer"}}- **操作人**: {{.Operator}}
+---
+{{.MentionText}}{{end}}`
+
+// builtin 是各事件类型的内置模板，键与 handler 中的事件类型一致
+var builtin = map[string]string{
+	"created": `{{define "title"}}新任务创建{{end}}
+{{- define "item"}}- **摘要名称**: {{link .Issue.Summary .Issue.Link}}
+{{end}}`,
+
+	"deleted": `{{define "title"}}任务被删除{{end}}
+{{- define "item"}}- **摘要名称**: {{strike (printf "%s %s" .Issue.Key .Issue.Summary)}}
+{{end}}`,
+
+	"updated_status": `{{define "title"}}任务状态变更{{end}}
+{{- define "item"}}- **摘要名称**: {{link .Issue.Summary .Issue.Link}}
+-  **状态**: {{strike .Change.From}} → **{{.Change.To}}**
+{{end}}`,
+
+	"updated_report": `{{define "title"}}任务报告人变更{{end}}
+{{- define "item"}}- **摘要名称**: {{link .Issue.Summary .Issue.Link}}
+- **报告人**: {{strike .Change.From}} → **{{.Change.To}}**
+{{end}}`,
+
+	"updated_assigner": `{{define "title"}}任务经办人变更{{end}}
+{{- define "item"}}- **摘要名称**: {{link .Issue.Summary .Issue.Link}}
+- **经办人**: {{with .Change.From}}{{strike .}} {{end}}→ **{{.Change.To}}**
+{{end}}`,
+
+	"updated_field": `{{define "title"}}任务字段变更{{end}}
+{{- define "item"}}- **摘要名称**: {{link .Issue.Summary .Issue.Link}}
+- **{{.Change.Field}}**: {{strike .Change.From}} → **{{.Change.To}}**
+{{end}}`,
+
+	"comment_created": `{{define "title"}}新评论{{end}}
+{{- define "item"}}- **摘要名称**: {{link .Issue.Summary .Issue.Link}}
+> {{truncate 200 .Comment}}
+{{end}}`,
+
+	"comment_updated": `{{define "title"}}评论被编辑{{end}}
+{{- define "item"}}- **摘要名称**: {{link .Issue.Summary .Issue.Link}}
+> {{truncate 200 .Comment}}
+{{end}}`,
+
+	"comment_deleted": `{{define "title"}}评论被删除{{end}}
+{{- define "item"}}- **摘要名称**: {{link .Issue.Summary .Issue.Link}}
+> {{strike (truncate 200 .Comment)}}
+{{end}}`,
+
+	genericEvent: `{{define "title"}}{{.EventType}}{{end}}
+{{- define "item"}}- **摘要名称**: {{link .Issue.Summary .Issue.Link}}
+{{end}}`,
+}
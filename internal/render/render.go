@@ -0,0 +1,219 @@
+package render
+
+import (
+	"bytes"
+	"errors"
+	"fmt"
+	"os"
+	"path/filepath"
+	"text/template"
+)
+
+// Issue 是模板中的问题信息
+type Issue struct {
+	Key     string
+	Summary string
+	Link    string
+	Status  string
+}
+
+// Change 是一条字段变更
+type Change struct {
+	Field string
+	From  string
+	To    string
+}
+
+// Item 是汇总消息中的一条事件
+type Item struct {
+	Issue  Issue
+	Change Change
+	// Comment 是评论事件的评论正文
+	Comment string
+}
+
+// Digest 是一次去抖汇总的模板数据
+type Digest struct {
+	EventType string
+	Operator  string
+	Items     []Item
+	// Mentions 是需要 @ 的手机号或钉钉 userId
+	Mentions []string
+	// Part 和 Parts 是拆分后的序号和总数，未拆分时为 0
+	Part, Parts int
+}
+
+// PartLabel 返回拆分后的编号，例如 1/3，未拆分时为空
+func (d Digest) PartLabel() string {
+	if d.Parts <= 1 {
+		return ""
+	}
+	return fmt.Sprintf("%d/%d", d.Part, d.Parts)
+}
+
+// MentionText 返回消息正文中的 @ 文本
+func (d Digest) MentionText() string {
+	var mentions string
+	for _, m := range d.Mentions {
+		if m != "" {
+			mentions += fmt.Sprintf("@%s ", m)
+		}
+	}
+	return mentions
+}
+
+// funcs 是模板中可用的辅助函数
+var funcs = template.FuncMap{
+	// link 生成 markdown 链接，url 为空时只输出文本
+	"link": func(text, url string) string {
+		if url == "" {
+			return text
+		}
+		return fmt.Sprintf("[%s](%s)", text, url)
+	},
+	// strike 生成删除线，空字符串原样返回
+	"strike": func(s string) string {
+		if s == "" {
+			return ""
+		}
+		return "~~" + s + "~~"
+	},
+	// bold 生成加粗文本
+	"bold": func(s string) string {
+		if s == "" {
+			return ""
+		}
+		return "**" + s + "**"
+	},
+	// truncate 按字符数截断，超出部分以省略号代替
+	"truncate": func(n int, s string) string {
+		runes := []rune(s)
+		if n <= 0 || len(runes) <= n {
+			return s
+		}
+		return string(runes[:n]) + "…"
+	},
+}
+
+// Templates 是按机器人和事件类型组织的消息模板
+type Templates struct {
+	sets map[string]map[string]*template.Template
+	// builtin 只包含内置模板，自定义模板运行时渲染失败时兜底
+	builtin map[string]*template.Template
+}
+
+// Load 为每个机器人加载全部事件类型的模板
+// 在内置模板基础上依次叠加 dir/common.tmpl、dir/<事件类型>.tmpl、
+// dir/<机器人>/common.tmpl、dir/<机器人>/<事件类型>.tmpl，后加载的同名 define 覆盖先前的定义
+// 解析或试渲染失败时返回错误，保证格式有误的模板在启动时就被发现
+func Load(dir string, robots []string) (*Templates, error) {
+	t := &Templates{
+		sets:    make(map[string]map[string]*template.Template),
+		builtin: make(map[string]*template.Template),
+	}
+
+	var errs []error
+	for eventType := range builtin {
+		tmpl, err := buildTemplate("", "builtin", eventType)
+		if err != nil {
+			errs = append(errs, err)
+			continue
+		}
+		t.builtin[eventType] = tmpl
+	}
+	for _, robot := range robots {
+		t.sets[robot] = make(map[string]*template.Template)
+		for eventType := range builtin {
+			tmpl, err := buildTemplate(dir, robot, eventType)
+			if err != nil {
+				errs = append(errs, err)
+				continue
+			}
+			t.sets[robot][eventType] = tmpl
+		}
+	}
+	if len(errs) > 0 {
+		return nil, errors.Join(errs...)
+	}
+	return t, nil
+}
+
+// buildTemplate 构造并试渲染某个机器人、某类事件的模板
+func buildTemplate(dir, robot, eventType string) (*template.Template, error) {
+	tmpl, err := template.New(robot + "/" + eventType).Funcs(funcs).Parse(builtinCommon)
+	if err != nil {
+		return nil, fmt.Errorf("内置模板 common: %v", err)
+	}
+	if _, err := tmpl.Parse(builtin[eventType]); err != nil {
+		return nil, fmt.Errorf("内置模板 %s: %v", eventType, err)
+	}
+
+	if dir != "" {
+		overrides := []string{
+			filepath.Join(dir, "common.tmpl"),
+			filepath.Join(dir, eventType+".tmpl"),
+			filepath.Join(dir, robot, "common.tmpl"),
+			filepath.Join(dir, robot, eventType+".tmpl"),
+		}
+		for _, filePath := range overrides {
+			data, err := os.ReadFile(filePath)
+			if os.IsNotExist(err) {
+				continue
+			}
+			if err != nil {
+				return nil, fmt.Errorf("读取模板 %s 失败: %v", filePath, err)
+			}
+			if _, err := tmpl.Parse(string(data)); err != nil {
+				return nil, fmt.Errorf("解析模板 %s 失败: %v", filePath, err)
+			}
+		}
+	}
+
+	// 用示例数据试渲染，提前发现引用了不存在字段等执行期错误
+	if err := tmpl.ExecuteTemplate(new(bytes.Buffer), "message", sampleDigest(eventType)); err != nil {
+		return nil, fmt.Errorf("模板 %s/%s 渲染失败: %v", robot, eventType, err)
+	}
+	return tmpl, nil
+}
+
+// sampleDigest 返回用于试渲染的示例数据
+func sampleDigest(eventType string) Digest {
+	return Digest{
+		EventType: eventType,
+		Operator:  "示例操作人",
+		Items: []Item{{
+			Issue:   Issue{Key: "DEMO-1", Summary: "示例任务", Link: "https://jira.example.com/browse/DEMO-1", Status: "进行中"},
+			Change:  Change{Field: "status", From: "待办", To: "进行中"},
+			Comment: "示例评论 @示例经办人",
+		}},
+		Mentions: []string{"13800000000"},
+	}
+}
+
+// Render 渲染汇总消息，未知的事件类型使用通用模板
+func (t *Templates) Render(robot string, d Digest) (string, error) {
+	set, ok := t.sets[robot]
+	if !ok {
+		return "", fmt.Errorf("未加载机器人 %s 的模板", robot)
+	}
+	return execute(set, d)
+}
+
+// RenderBuiltin 只使用内置模板渲染汇总消息
+func (t *Templates) RenderBuiltin(d Digest) (string, error) {
+	return execute(t.builtin, d)
+}
+
+// execute 按事件类型选择模板并渲染，未知的事件类型使用通用模板
+func execute(set map[string]*template.Template, d Digest) (string, error) {
+	tmpl, ok := set[d.EventType]
+	if !ok {
+		tmpl = set[genericEvent]
+	}
+
+	var buf bytes.Buffer
+	if err := tmpl.ExecuteTemplate(&buf, "message", d); err != nil {
+		return "", err
+	}
+	return buf.String(), nil
+}
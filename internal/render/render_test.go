@@ -0,0 +1,110 @@
+package render
+
+import (
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+)
+
+func TestRenderBuiltin(t *testing.T) {
+	tmpls, err := Load("", []string{"default"})
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	got, err := tmpls.Render("default", Digest{
+		EventType: "updated_status",
+		Operator:  "张三",
+		Items: []Item{{
+			Issue:  Issue{Key: "ABC-1", Summary: "登录失败", Link: "https://jira/browse/ABC-1"},
+			Change: Change{Field: "status", From: "待办", To: "进行中"},
+		}},
+		Mentions: []string{"138"},
+	})
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	want := "\n### **事件通知: 任务状态变更**             \n" +
+		"- **摘要名称**: [登录失败](https://jira/browse/ABC-1)\n" +
+		"-  **状态**: ~~待办~~ → **进行中**\n" +
+		"\n- **操作人**: 张三\n---\n@138 "
+	if got != want {
+		t.Errorf("got:\n%q\nwant:\n%q", got, want)
+	}
+}
+
+func TestLoadOverrides(t *testing.T) {
+	dir := t.TempDir()
+	if err := os.MkdirAll(filepath.Join(dir, "qa"), 0o755); err != nil {
+		t.Fatal(err)
+	}
+	if err := os.WriteFile(filepath.Join(dir, "created.tmpl"), []byte(`{{define "title"}}新需求{{end}}`), 0o600); err != nil {
+		t.Fatal(err)
+	}
+	if err := os.WriteFile(filepath.Join(dir, "qa", "created.tmpl"), []byte(`{{define "item"}}* {{truncate 2 .Issue.Summary}}
+{{end}}`), 0o600); err != nil {
+		t.Fatal(err)
+	}
+
+	tmpls, err := Load(dir, []string{"default", "qa"})
+	if err != nil {
+		t.Fatal(err)
+	}
+	d := Digest{EventType: "created", Items: []Item{{Issue: Issue{Summary: "登录失败"}}}}
+
+	got, _ := tmpls.Render("default", d)
+	if !strings.Contains(got, "事件通知: 新需求") || !strings.Contains(got, "- **摘要名称**: 登录失败") {
+		t.Errorf("default got %q", got)
+	}
+	got, _ = tmpls.Render("qa", d)
+	if !strings.Contains(got, "事件通知: 新需求") || !strings.Contains(got, "* 登录…") {
+		t.Errorf("qa got %q", got)
+	}
+}
+
+func TestLoadRejectsMalformed(t *testing.T) {
+	dir := t.TempDir()
+	if err := os.WriteFile(filepath.Join(dir, "deleted.tmpl"), []byte(`{{define "item"}}{{.Issue.Nope}}{{end}}`), 0o600); err != nil {
+		t.Fatal(err)
+	}
+	if _, err := Load(dir, []string{"default"}); err == nil || !strings.Contains(err.Error(), "default/deleted") {
+		t.Fatalf("err = %v", err)
+	}
+
+	if err := os.WriteFile(filepath.Join(dir, "deleted.tmpl"), []byte(`{{define "item"}}{{if}}{{end}}`), 0o600); err != nil {
+		t.Fatal(err)
+	}
+	if _, err := Load(dir, []string{"default"}); err == nil || !strings.Contains(err.Error(), "deleted.tmpl") {
+		t.Fatalf("err = %v", err)
+	}
+}
+
+func TestRenderBuiltinFallback(t *testing.T) {
+	dir := t.TempDir()
+	// 只有一条事件的示例数据可以渲染，运行时多条事件时出错
+	if err := os.WriteFile(filepath.Join(dir, "updated_status.tmpl"),
+		[]byte(`{{define "title"}}{{if gt (len .Items) 1}}{{index .Items 5}}{{end}}状态{{end}}`), 0o600); err != nil {
+		t.Fatal(err)
+	}
+	tmpls, err := Load(dir, []string{"default"})
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	d := Digest{EventType: "updated_status", Operator: "张三", Mentions: []string{"138"}, Items: []Item{
+		{Issue: Issue{Summary: "a"}, Change: Change{From: "待办", To: "进行中"}},
+		{Issue: Issue{Summary: "b"}, Change: Change{From: "进行中", To: "完成"}},
+	}}
+	if _, err := tmpls.RenderParts("default", d, 20000); err == nil {
+		t.Fatal("custom template rendered without error")
+	}
+	parts, err := tmpls.RenderBuiltinParts(d, 20000)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(parts) != 1 || !strings.Contains(parts[0], "事件通知: 任务状态变更") || !strings.Contains(parts[0], "@138") {
+		t.Errorf("builtin parts = %q", parts)
+	}
+}
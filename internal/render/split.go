@@ -0,0 +1,120 @@
+package render
+
+import "unicode/utf8"
+
+// RenderParts 渲染汇总消息，超过 maxBytes 时在事件之间拆分为多条编号消息
+// 只有最后一条保留 @，避免同一批事件重复提醒；单条事件本身超长时截断该事件的内容，保留标题和 @
+func (t *Templates) RenderParts(robot string, d Digest, maxBytes int) ([]string, error) {
+	return renderParts(func(d Digest) (string, error) { return t.Render(robot, d) }, d, maxBytes)
+}
+
+// RenderBuiltinParts 与 RenderParts 相同，但只使用内置模板
+func (t *Templates) RenderBuiltinParts(d Digest, maxBytes int) ([]string, error) {
+	return renderParts(t.RenderBuiltin, d, maxBytes)
+}
+
+// renderParts 用 render 渲染汇总消息，按 maxBytes 拆分
+func renderParts(render func(Digest) (string, error), d Digest, maxBytes int) ([]string, error) {
+	whole, err := render(d)
+	if err != nil {
+		return nil, err
+	}
+	if len(whole) <= maxBytes {
+		return []string{whole}, nil
+	}
+	if len(d.Items) <= 1 {
+		s, err := fitItem(render, d, whole, maxBytes)
+		if err != nil {
+			return nil, err
+		}
+		return []string{s}, nil
+	}
+
+	// 按最宽的编号和完整的 @ 试渲染，确定编号后每条消息都不会超长
+	probe := d
+	probe.Part, probe.Parts = len(d.Items), len(d.Items)
+	var groups [][]Item
+	for start := 0; start < len(d.Items); {
+		end := start + 1
+		for ; end < len(d.Items); end++ {
+			probe.Items = d.Items[start : end+1]
+			s, err := render(probe)
+			if err != nil {
+				return nil, err
+			}
+			if len(s) > maxBytes {
+				break
+			}
+		}
+		groups = append(groups, d.Items[start:end])
+		start = end
+	}
+
+	parts := make([]string, 0, len(groups))
+	for i, items := range groups {
+		part := d
+		part.Items = items
+		part.Part, part.Parts = i+1, len(groups)
+		if part.Part < part.Parts {
+			part.Mentions = nil
+		}
+		s, err := render(part)
+		if err != nil {
+			return nil, err
+		}
+		if s, err = fitItem(render, part, s, maxBytes); err != nil {
+			return nil, err
+		}
+		parts = append(parts, s)
+	}
+	return parts, nil
+}
+
+// fitItem 在只有一条事件且渲染结果 s 超长时，逐次截断该事件最长的文本字段后重新渲染，
+// 标题和末尾的 @ 保持完整；仍然超长时才截断整条消息
+func fitItem(render func(Digest) (string, error), d Digest, s string, maxBytes int) (string, error) {
+	if len(s) <= maxBytes || len(d.Items) != 1 {
+		return TruncateBytes(s, maxBytes), nil
+	}
+	item := d.Items[0]
+	d.Items = []Item{item}
+	for len(s) > maxBytes {
+		field := longestField(&d.Items[0])
+		if *field == "" {
+			return TruncateBytes(s, maxBytes), nil
+		}
+		*field = TruncateBytes(*field, len(*field)-(len(s)-maxBytes))
+		var err error
+		if s, err = render(d); err != nil {
+			return "", err
+		}
+	}
+	return s, nil
+}
+
+// longestField 返回事件中可截断的最长文本字段
+func longestField(item *Item) *string {
+	longest := &item.Issue.Summary
+	for _, f := range []*string{&item.Comment, &item.Change.From, &item.Change.To} {
+		if len(*f) > len(*longest) {
+			longest = f
+		}
+	}
+	return longest
+}
+
+// TruncateBytes 将 s 截断到不超过 maxBytes 字节，不拆开多字节字符，截断时以省略号结尾
+func TruncateBytes(s string, maxBytes int) string {
+	if len(s) <= maxBytes {
+		return s
+	}
+	const ellipsis = "…"
+	n := maxBytes - len(ellipsis)
+	if n <= 0 {
+		return ""
+	}
+	for n > 0 && !utf8.RuneStart(s[n]) {
+		n--
+	}
+	return s[:n] + ellipsis
+}
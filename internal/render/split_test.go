@@ -0,0 +1,84 @@
+package render
+
+import (
+	"fmt"
+	"strings"
+	"testing"
+)
+
+func TestRenderPartsSplitsAtItems(t *testing.T) {
+	tmpls, err := Load("", []string{"default"})
+	if err != nil {
+		t.Fatal(err)
+	}
+	d := Digest{EventType: "created", Operator: "张三", Mentions: []string{"138"}}
+	for i := 0; i < 30; i++ {
+		key := fmt.Sprintf("ABC-%d", i)
+		d.Items = append(d.Items, Item{Issue: Issue{Key: key, Summary: "任务" + key, Link: "https://jira/browse/" + key}})
+	}
+
+	parts, err := tmpls.RenderParts("default", d, 1000)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(parts) < 2 {
+		t.Fatalf("got %d parts, want more than 1", len(parts))
+	}
+	seen := 0
+	for i, p := range parts {
+		if len(p) > 1000 {
+			t.Errorf("part %d is %d bytes", i+1, len(p))
+		}
+		if label := fmt.Sprintf("(%d/%d)", i+1, len(parts)); !strings.Contains(p, label) {
+			t.Errorf("part %d missing label %s", i+1, label)
+		}
+		if last := i == len(parts)-1; strings.Contains(p, "@138") != last {
+			t.Errorf("part %d mention present = %v, want %v", i+1, !last, last)
+		}
+		seen += strings.Count(p, "- **摘要名称**")
+	}
+	if seen != len(d.Items) {
+		t.Errorf("items across parts = %d, want %d", seen, len(d.Items))
+	}
+
+	whole, _ := tmpls.RenderParts("default", d, 1<<20)
+	if len(whole) != 1 || strings.Contains(whole[0], "(1/") {
+		t.Errorf("small digest should not be split: %q", whole)
+	}
+}
+
+func TestTruncateBytes(t *testing.T) {
+	if got := TruncateBytes("登录失败", 9); got != "登录…" {
+		t.Errorf("got %q", got)
+	}
+	if got := TruncateBytes("abc", 3); got != "abc" {
+		t.Errorf("got %q", got)
+	}
+}
+
+func TestRenderPartsTruncatesOversizedItem(t *testing.T) {
+	tmpls, err := Load("", []string{"default"})
+	if err != nil {
+		t.Fatal(err)
+	}
+	d := Digest{EventType: "created", Operator: "张三", Mentions: []string{"138"}, Items: []Item{{
+		Issue: Issue{Key: "ABC-1", Summary: strings.Repeat("很长的摘要", 200), Link: "https://jira/browse/ABC-1"},
+	}}}
+
+	parts, err := tmpls.RenderParts("default", d, 500)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(parts) != 1 {
+		t.Fatalf("got %d parts, want 1", len(parts))
+	}
+	p := parts[0]
+	if len(p) > 500 {
+		t.Errorf("part is %d bytes", len(p))
+	}
+	for _, want := range []string{"ABC-1", "很长的摘要…", "@138"} {
+		if !strings.Contains(p, want) {
+			t.Errorf("part missing %q: %q", want, p)
+		}
+	}
+}
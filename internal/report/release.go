@@ -0,0 +1,104 @@
+package report
+
+import (
+	"fmt"
+	"sort"
+	"strings"
+)
+
+// releaseTypeOrder 是发布说明中问题类型的排列顺序，其余类型按名称排在后面
+var releaseTypeOrder = []string{"Bug", "Story", "Task"}
+
+// ReleaseIssue 是发布说明中的一个问题
+type ReleaseIssue struct {
+	Key     string
+	Summary string
+	Link    string
+	Type    string
+	Status  string
+	Done    bool
+}
+
+// Release 是一个版本的发布说明
+type Release struct {
+	Name        string
+	Description string
+	ReleaseDate string
+	Issues      []ReleaseIssue
+}
+
+// ReleaseGroup 是同一问题类型下的问题
+type ReleaseGroup struct {
+	Type   string
+	Issues []ReleaseIssue
+}
+
+// Groups 按问题类型分组，Bug、Story、Task 在前
+func (r Release) Groups() []ReleaseGroup {
+	byType := make(map[string][]ReleaseIssue)
+	for _, i := range r.Issues {
+		t := i.Type
+		if t == "" {
+			t = "Other"
+		}
+		byType[t] = append(byType[t], i)
+	}
+
+	var types []string
+	for t := range byType {
+		types = append(types, t)
+	}
+	sort.Slice(types, func(i, j int) bool {
+		ri, rj := typeRank(types[i]), typeRank(types[j])
+		if ri != rj {
+			return ri < rj
+		}
+		return types[i] < types[j]
+	})
+
+	groups := make([]ReleaseGroup, 0, len(types))
+	for _, t := range types {
+		issues := byType[t]
+		sort.Slice(issues, func(i, j int) bool { return issues[i].Key < issues[j].Key })
+		groups = append(groups, ReleaseGroup{Type: t, Issues: issues})
+	}
+	return groups
+}
+
+func typeRank(t string) int {
+	for i, name := range releaseTypeOrder {
+		if strings.EqualFold(t, name) {
+			return i
+		}
+	}
+	return len(releaseTypeOrder)
+}
+
+// Markdown 生成发布说明，未完成的问题标注当前状态
+func (r Release) Markdown() string {
+	var b strings.Builder
+	fmt.Fprintf(&b, "### **版本发布: %s**\n", r.Name)
+	if r.ReleaseDate != "" {
+		fmt.Fprintf(&b, "- **发布日期**: %s\n", r.ReleaseDate)
+	}
+	if r.Description != "" {
+		fmt.Fprintf(&b, "- **说明**: %s\n", r.Description)
+	}
+	for _, g := range r.Groups() {
+		fmt.Fprintf(&b, "\n#### %s (%d)\n", g.Type, len(g.Issues))
+		for _, i := range g.Issues {
+			text := i.Key + " " + i.Summary
+			if i.Link != "" {
+				text = fmt.Sprintf("[%s](%s)", text, i.Link)
+			}
+			if !i.Done && i.Status != "" {
+				text += fmt.Sprintf("（%s）", i.Status)
+			}
+			fmt.Fprintf(&b, "- %s\n", text)
+		}
+	}
+	if len(r.Issues) == 0 {
+		b.WriteString("\n- 没有记录到修复版本为该版本的问题\n")
+	}
+	return b.String()
+}
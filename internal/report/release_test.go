@@ -0,0 +1,26 @@
+package report
+
+import (
+	"strings"
+	"testing"
+)
+
+func TestReleaseGroups(t *testing.T) {
+	r := Release{Issues: []ReleaseIssue{
+		{Key: "ABC-3", Type: "Task"},
+		{Key: "ABC-2", Type: "Epic"},
+		{Key: "ABC-5", Type: "Bug"},
+		{Key: "ABC-1", Type: "Bug"},
+		{Key: "ABC-4", Type: "Story"},
+	}}
+	var got []string
+	for _, g := range r.Groups() {
+		for _, i := range g.Issues {
+			got = append(got, g.Type+":"+i.Key)
+		}
+	}
+	want := "Bug:ABC-1 Bug:ABC-5 Story:ABC-4 Task:ABC-3 Epic:ABC-2"
+	if strings.Join(got, " ") != want {
+		t.Errorf("groups = %v, want %s", got, want)
+	}
+}
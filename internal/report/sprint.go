@@ -0,0 +1,165 @@
+package report
+
+import (
+	"fmt"
+	"sort"
+	"strings"
+	"time"
+	"whenchangesth/internal/objects"
+)
+
+// unassigned 是没有经办人的问题的分组名
+const unassigned = "未分配"
+
+// SprintIssue 是 Sprint 中的一个问题
+type SprintIssue struct {
+	Key      string
+	Summary  string
+	Link     string
+	Assignee string
+	Status   string
+	Done     bool
+	// AddedAt 是变更记录中问题加入 Sprint 的时间，首次见到时已在 Sprint 中的问题为零值
+	AddedAt time.Time
+}
+
+// AssigneeThroughput 是某个经办人在 Sprint 中的完成情况
+type AssigneeThroughput struct {
+	Assignee  string
+	Completed int
+	Total     int
+}
+
+// SprintSummary 是 Sprint 关闭时的统计
+type SprintSummary struct {
+	Completed   int
+	CarriedOver int
+	// Added 是 Sprint 开始后加入的问题
+	Added      []SprintIssue
+	Throughput []AssigneeThroughput
+}
+
+// SummarizeSprint 统计 Sprint 的完成、遗留和中途加入的问题
+func SummarizeSprint(s *objects.Sprint, issues []SprintIssue) SprintSummary {
+	var sum SprintSummary
+	byAssignee := make(map[string]*AssigneeThroughput)
+	for _, i := range issues {
+		if i.Done {
+			sum.Completed++
+		} else {
+			sum.CarriedOver++
+		}
+		if s.StartDate != nil && !i.AddedAt.IsZero() && i.AddedAt.After(*s.StartDate) {
+			sum.Added = append(sum.Added, i)
+		}
+
+		name := assigneeName(i)
+		t, ok := byAssignee[name]
+		if !ok {
+			t = &AssigneeThroughput{Assignee: name}
+			byAssignee[name] = t
+		}
+		t.Total++
+		if i.Done {
+			t.Completed++
+		}
+	}
+	for _, t := range byAssignee {
+		sum.Throughput = append(sum.Throughput, *t)
+	}
+	sort.Slice(sum.Throughput, func(i, j int) bool {
+		a, b := sum.Throughput[i], sum.Throughput[j]
+		if a.Completed != b.Completed {
+			return a.Completed > b.Completed
+		}
+		return a.Assignee < b.Assignee
+	})
+	return sum
+}
+
+// SprintStartMarkdown 生成 Sprint 开始的消息，列出目标、起止时间和按经办人分组的承诺问题
+func SprintStartMarkdown(s *objects.Sprint, issues []SprintIssue) string {
+	var b strings.Builder
+	fmt.Fprintf(&b, "### **Sprint 开始: %s**\n", s.Name)
+	writeSprintHeader(&b, s)
+
+	groups := make(map[string][]SprintIssue)
+	for _, i := range issues {
+		groups[assigneeName(i)] = append(groups[assigneeName(i)], i)
+	}
+	names := make([]string, 0, len(groups))
+	for name := range groups {
+		names = append(names, name)
+	}
+	sort.Strings(names)
+
+	fmt.Fprintf(&b, "\n**承诺问题** (%d):\n", len(issues))
+	for _, name := range names {
+		fmt.Fprintf(&b, "- **%s** (%d)\n", name, len(groups[name]))
+		for _, i := range groups[name] {
+			fmt.Fprintf(&b, "    - %s\n", issueLine(i))
+		}
+	}
+	if len(issues) == 0 {
+		b.WriteString("- 暂无记录\n")
+	}
+	return b.String()
+}
+
+// SprintCloseMarkdown 生成 Sprint 关闭的报告
+func SprintCloseMarkdown(s *objects.Sprint, issues []SprintIssue) string {
+	sum := SummarizeSprint(s, issues)
+
+	var b strings.Builder
+	fmt.Fprintf(&b, "### **Sprint 关闭: %s**\n", s.Name)
+	writeSprintHeader(&b, s)
+	fmt.Fprintf(&b, "- **完成**: %d\n- **遗留**: %d\n- **中途加入**: %d\n", sum.Completed, sum.CarriedOver, len(sum.Added))
+
+	if len(sum.Added) > 0 {
+		b.WriteString("\n**中途加入的问题**:\n")
+		for _, i := range sum.Added {
+			fmt.Fprintf(&b, "- %s\n", issueLine(i))
+		}
+	}
+	if len(sum.Throughput) > 0 {
+		b.WriteString("\n**经办人完成情况**:\n")
+		for _, t := range sum.Throughput {
+			fmt.Fprintf(&b, "- %s: %d/%d\n", t.Assignee, t.Completed, t.Total)
+		}
+	}
+	return b.String()
+}
+
+// writeSprintHeader 写入 Sprint 目标和起止时间
+func writeSprintHeader(b *strings.Builder, s *objects.Sprint) {
+	if s.Goal != "" {
+		fmt.Fprintf(b, "- **目标**: %s\n", s.Goal)
+	}
+	fmt.Fprintf(b, "- **时间**: %s ~ %s\n", formatDate(s.StartDate), formatDate(s.EndDate))
+}
+
+func formatDate(t *time.Time) string {
+	if t == nil {
+		return "未设置"
+	}
+	return t.In(time.Local).Format("2006-01-02")
+}
+
+func assigneeName(i SprintIssue) string {
+	if i.Assignee == "" {
+		return unassigned
+	}
+	return i.Assignee
+}
+
+// issueLine 返回问题的单行展示
+func issueLine(i SprintIssue) string {
+	text := i.Key + " " + i.Summary
+	if i.Link != "" {
+		text = fmt.Sprintf("[%s](%s)", text, i.Link)
+	}
+	if i.Status != "" {
+		text += " · " + i.Status
+	}
+	return text
+}
@@ -0,0 +1,41 @@
+package report
+
+import (
+	"strings"
+	"testing"
+	"time"
+	"whenchangesth/internal/objects"
+)
+
+func TestSummarizeSprint(t *testing.T) {
+	start := time.Date(2026, 10, 1, 10, 0, 0, 0, time.Local)
+	s := &objects.Sprint{Name: "Sprint 3", StartDate: &start}
+	issues := []SprintIssue{
+		{Key: "ABC-1", Assignee: "张三", Done: true, AddedAt: start.Add(-time.Hour)},
+		{Key: "ABC-2", Assignee: "张三", AddedAt: start.Add(-time.Hour)},
+		{Key: "ABC-3", Assignee: "李四", Done: true, AddedAt: start.Add(48 * time.Hour)},
+		{Key: "ABC-4", Done: true}, // 首次见到时已在 Sprint 中，加入时间未知
+		{Key: "ABC-5", Assignee: "李四", Done: true, AddedAt: start.Add(-time.Hour)},
+	}
+
+	sum := SummarizeSprint(s, issues)
+	if sum.Completed != 4 || sum.CarriedOver != 1 {
+		t.Fatalf("completed = %d, carried over = %d", sum.Completed, sum.CarriedOver)
+	}
+	if len(sum.Added) != 1 || sum.Added[0].Key != "ABC-3" {
+		t.Fatalf("added = %+v", sum.Added)
+	}
+	want := []AssigneeThroughput{{"李四", 2, 2}, {"张三", 1, 2}, {unassigned, 1, 1}}
+	if len(sum.Throughput) != len(want) {
+		t.Fatalf("throughput = %+v", sum.Throughput)
+	}
+	for i := range want {
+		if sum.Throughput[i] != want[i] {
+			t.Errorf("throughput[%d] = %+v, want %+v", i, sum.Throughput[i], want[i])
+		}
+	}
+
+	if md := SprintCloseMarkdown(s, issues); !strings.Contains(md, "- **完成**: 4") || !strings.Contains(md, "- 李四: 2/2") {
+		t.Errorf("markdown = %s", md)
+	}
+}
@@ -0,0 +1,52 @@
+package rules
+
+// 与 pkg.Event 对应的事件名称
+const (
+	EventIssueCreated = "jira:issue_created"
+	EventIssueUpdated = "jira:issue_updated"
+	EventIssueDeleted = "jira:issue_deleted"
+
+	EventCommentCreated = "comment_created"
+	EventCommentUpdated = "comment_updated"
+	EventCommentDeleted = "comment_deleted"
+)
+
+// Defaults 返回未配置规则时使用的内置规则
+// 问题事件通知的字段与原先写死在处理器中的相同，但不再区分 issue_event_type_name：
+// 原先 issue_updated 只通知报告人变更、issue_assigned 只通知经办人变更、issue_generic 只通知状态变更，
+// 现在三者中的每条变更记录都按全部规则评估；评论事件 @ 经办人和评论中提到的人
+func Defaults() []Rule {
+	mentionBoth := []string{RoleAssignee, RoleReporter}
+	return []Rule{
+		{
+			Name:    "创建时已指定经办人",
+			Match:   Match{Events: []string{EventIssueCreated}, Fields: []string{"assignee"}},
+			Actions: Actions{Mention: mentionBoth},
+		},
+		{
+			Name:    "任务被删除",
+			Match:   Match{Events: []string{EventIssueDeleted}},
+			Actions: Actions{Mention: mentionBoth},
+		},
+		{
+			Name:    "报告人变更",
+			Match:   Match{Events: []string{EventIssueUpdated}, Fields: []string{"reporter"}},
+			Actions: Actions{Mention: mentionBoth},
+		},
+		{
+			Name:    "经办人变更",
+			Match:   Match{Events: []string{EventIssueUpdated}, Fields: []string{"assignee"}},
+			Actions: Actions{Mention: mentionBoth},
+		},
+		{
+			Name:    "状态变更",
+			Match:   Match{Events: []string{EventIssueUpdated}, Fields: []string{"status"}},
+			Actions: Actions{Mention: mentionBoth},
+		},
+		{
+			Name:    "评论",
+			Match:   Match{Events: []string{EventCommentCreated, EventCommentUpdated, EventCommentDeleted}},
+			Actions: Actions{Mention: []string{RoleAssignee, RoleMentioned}},
+		},
+	}
+}
@@ -0,0 +1,159 @@
+package rules
+
+import "strings"
+
+// 可以 @ 的角色
+const (
+	RoleAssignee = "assignee"
+	RoleReporter = "reporter"
+	RoleCreator  = "creator"
+	RoleWatchers = "watchers"
+	RoleOperator = "operator"
+	// RoleMentioned 是评论中 @ 到的人，只对评论事件生效
+	RoleMentioned = "mentioned"
+)
+
+// Roles 是 mention 动作支持的全部角色
+var Roles = []string{RoleAssignee, RoleReporter, RoleCreator, RoleWatchers, RoleOperator, RoleMentioned}
+
+// Rule 是一条通知规则：事件满足 Match 时执行 Actions
+type Rule struct {
+	Name    string  `yaml:"name"`
+	Match   Match   `yaml:"match"`
+	Actions Actions `yaml:"actions"`
+}
+
+// Match 是规则的匹配条件
+// 同一字段内的多个值任一命中即可，不同字段之间需要同时命中，未配置的字段不参与匹配
+type Match struct {
+	Events     []string `yaml:"events"`
+	Fields     []string `yaml:"fields"`
+	From       []string `yaml:"from"`
+	To         []string `yaml:"to"`
+	Projects   []string `yaml:"projects"`
+	Priorities []string `yaml:"priorities"`
+	IssueTypes []string `yaml:"issue_types"`
+	Labels     []string `yaml:"labels"`
+}
+
+// Actions 是规则命中后的动作
+type Actions struct {
+	// Notify 指定发送的机器人，为空时使用路由表
+	Notify []string `yaml:"notify"`
+	// Mention 指定需要 @ 的角色
+	Mention []string `yaml:"mention"`
+	// Skip 命中后丢弃事件并停止评估后续规则
+	Skip bool `yaml:"skip"`
+	// Immediate 命中后跳过去抖立即发送
+	Immediate bool `yaml:"immediate"`
+}
+
+// Facts 是一次评估的输入，对应事件中的一条变更记录
+type Facts struct {
+	Event     string
+	Field     string
+	From      string
+	To        string
+	Project   string
+	Priority  string
+	IssueType string
+	Labels    []string
+}
+
+// Decision 是规则评估的结果
+type Decision struct {
+	Matched   []string
+	Notify    bool
+	Skipped   bool
+	Immediate bool
+	Robots    []string
+	Mention   []string
+}
+
+// Matches 判断事件是否满足规则的匹配条件
+func (r Rule) Matches(f Facts) bool {
+	m := r.Match
+	return matchAny(m.Events, f.Event) &&
+		matchAny(m.Fields, f.Field) &&
+		matchAny(m.From, f.From) &&
+		matchAny(m.To, f.To) &&
+		matchAny(m.Projects, f.Project) &&
+		matchAny(m.Priorities, f.Priority) &&
+		matchAny(m.IssueTypes, f.IssueType) &&
+		matchAny(m.Labels, f.Labels...)
+}
+
+// Evaluate 按顺序评估全部规则，合并所有命中规则的动作
+// 命中 skip 规则时立即停止，事件不发送通知
+func Evaluate(rules []Rule, f Facts) Decision {
+	var d Decision
+	for _, r := range rules {
+		if !r.Matches(f) {
+			continue
+		}
+		d.Matched = append(d.Matched, r.Name)
+		if r.Actions.Skip {
+			d.Skipped = true
+			d.Notify = false
+			return d
+		}
+		d.Notify = true
+		d.Immediate = d.Immediate || r.Actions.Immediate
+		d.Robots = appendUnique(d.Robots, r.Actions.Notify...)
+		d.Mention = appendUnique(d.Mention, r.Actions.Mention...)
+	}
+	return d
+}
+
+// Merge 合并同一事件中多条变更记录的评估结果，只要有一条需要通知就发送
+func Merge(decisions ...Decision) Decision {
+	var merged Decision
+	for _, d := range decisions {
+		merged.Matched = appendUnique(merged.Matched, d.Matched...)
+		if d.Skipped {
+			merged.Skipped = true
+		}
+		if !d.Notify {
+			continue
+		}
+		merged.Notify = true
+		merged.Immediate = merged.Immediate || d.Immediate
+		merged.Robots = appendUnique(merged.Robots, d.Robots...)
+		merged.Mention = appendUnique(merged.Mention, d.Mention...)
+	}
+	if merged.Notify {
+		merged.Skipped = false
+	}
+	return merged
+}
+
+// matchAny 在 want 为空时视为命中，否则要求 values 中至少有一个在 want 内（不区分大小写）
+func matchAny(want []string, values ...string) bool {
+	if len(want) == 0 {
+		return true
+	}
+	for _, w := range want {
+		for _, v := range values {
+			if strings.EqualFold(w, v) {
+				return true
+			}
+		}
+	}
+	return false
+}
+
+func appendUnique(dst []string, items ...string) []string {
+	for _, item := range items {
+		found := false
+		for _, d := range dst {
+			if d == item {
+				found = true
+				break
+			}
+		}
+		if !found {
+			dst = append(dst, item)
+		}
+	}
+	return dst
+}
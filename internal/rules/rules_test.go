@@ -0,0 +1,53 @@
+package rules
+
+import (
+	"reflect"
+	"testing"
+)
+
+func TestEvaluate(t *testing.T) {
+	rs := []Rule{
+		{Name: "skip-low", Match: Match{Priorities: []string{"Low"}}, Actions: Actions{Skip: true}},
+		{Name: "status", Match: Match{Events: []string{EventIssueUpdated}, Fields: []string{"status"}}, Actions: Actions{Mention: []string{RoleAssignee}}},
+		{Name: "blocked", Match: Match{Fields: []string{"status"}, To: []string{"Blocked"}}, Actions: Actions{Notify: []string{"qa"}, Mention: []string{RoleAssignee, RoleOperator}, Immediate: true}},
+	}
+
+	d := Evaluate(rs, Facts{Event: EventIssueUpdated, Field: "status", To: "blocked", Priority: "High"})
+	if !d.Notify || !d.Immediate || d.Skipped {
+		t.Fatalf("decision = %+v", d)
+	}
+	if !reflect.DeepEqual(d.Matched, []string{"status", "blocked"}) {
+		t.Errorf("matched = %v", d.Matched)
+	}
+	if !reflect.DeepEqual(d.Mention, []string{RoleAssignee, RoleOperator}) {
+		t.Errorf("mention = %v", d.Mention)
+	}
+	if !reflect.DeepEqual(d.Robots, []string{"qa"}) {
+		t.Errorf("robots = %v", d.Robots)
+	}
+
+	d = Evaluate(rs, Facts{Event: EventIssueUpdated, Field: "status", To: "Blocked", Priority: "Low"})
+	if d.Notify || !d.Skipped || !reflect.DeepEqual(d.Matched, []string{"skip-low"}) {
+		t.Fatalf("skip decision = %+v", d)
+	}
+
+	d = Evaluate(rs, Facts{Event: EventIssueUpdated, Field: "labels"})
+	if d.Notify || len(d.Matched) != 0 {
+		t.Fatalf("unmatched decision = %+v", d)
+	}
+}
+
+func TestMerge(t *testing.T) {
+	d := Merge(
+		Decision{Matched: []string{"skip"}, Skipped: true},
+		Decision{Matched: []string{"a"}, Notify: true, Mention: []string{RoleReporter}},
+	)
+	if !d.Notify || d.Skipped || !reflect.DeepEqual(d.Matched, []string{"skip", "a"}) {
+		t.Fatalf("merged = %+v", d)
+	}
+
+	d = Merge(Decision{Skipped: true}, Decision{})
+	if d.Notify || !d.Skipped {
+		t.Fatalf("merged skip = %+v", d)
+	}
+}
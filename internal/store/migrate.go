@@ -0,0 +1,217 @@
+package store
+
+import (
+	"bufio"
+	"context"
+	"database/sql"
+	"embed"
+	"fmt"
+	"io/fs"
+	"path"
+	"sort"
+	"strconv"
+	"strings"
+	"time"
+)
+
+// migrationFS 是随程序发布的数据库迁移，文件名格式为 <版本号>_<名称>.sql，按版本号顺序执行
+//
+//go:embed migrations/*.sql
+var migrationFS embed.FS
+
+// migrateLock 是执行迁移时持有的 MySQL 命名锁，多个实例同时启动时只有一个执行迁移
+const migrateLock = "jirahook_migrate"
+
+const createMigrationsTable = `
+CREATE TABLE IF NOT EXISTS jirahook_schema_migrations (
+    version    INT          NOT NULL PRIMARY KEY,
+    name       VARCHAR(255) NOT NULL,
+    applied_at DATETIME     NOT NULL
+) DEFAULT CHARSET = utf8mb4`
+
+// Migration 是一个版本的数据库迁移
+type Migration struct {
+	Version    int
+	Name       string
+	Statements []string
+}
+
+// MigrationStatus 是迁移的执行情况，AppliedAt 为空表示尚未执行
+type MigrationStatus struct {
+	Migration
+	AppliedAt *time.Time
+}
+
+// Migrations 返回全部迁移，按版本号排序
+func Migrations() ([]Migration, error) {
+	return loadMigrations(migrationFS, "migrations")
+}
+
+// loadMigrations 读取目录中的迁移文件
+func loadMigrations(fsys fs.FS, dir string) ([]Migration, error) {
+	entries, err := fs.ReadDir(fsys, dir)
+	if err != nil {
+		return nil, err
+	}
+
+	var migrations []Migration
+	seen := make(map[int]string)
+	for _, entry := range entries {
+		if entry.IsDir() || path.Ext(entry.Name()) != ".sql" {
+			continue
+		}
+		base := strings.TrimSuffix(entry.Name(), ".sql")
+		num, name, ok := strings.Cut(base, "_")
+		version, err := strconv.Atoi(num)
+		if !ok || err != nil || version <= 0 {
+			return nil, fmt.Errorf("迁移文件名应为 <版本号>_<名称>.sql: %s", entry.Name())
+		}
+		if prev, dup := seen[version]; dup {
+			return nil, fmt.Errorf("迁移版本 %d 重复: %s, %s", version, prev, entry.Name())
+		}
+		seen[version] = entry.Name()
+
+		data, err := fs.ReadFile(fsys, path.Join(dir, entry.Name()))
+		if err != nil {
+			return nil, err
+		}
+		stmts := splitStatements(string(data))
+		if len(stmts) == 0 {
+			return nil, fmt.Errorf("迁移文件 %s 没有 SQL 语句", entry.Name())
+		}
+		migrations = append(migrations, Migration{Version: version, Name: name, Statements: stmts})
+	}
+	sort.Slice(migrations, func(i, j int) bool { return migrations[i].Version < migrations[j].Version })
+	return migrations, nil
+}
+
+// splitStatements 按行尾的分号拆分 SQL 语句，忽略 -- 开头的注释行
+func splitStatements(script string) []string {
+	var stmts []string
+	var cur strings.Builder
+	scanner := bufio.NewScanner(strings.NewReader(script))
+	for scanner.Scan() {
+		line := scanner.Text()
+		trimmed := strings.TrimSpace(line)
+		if trimmed == "" || strings.HasPrefix(trimmed, "--") {
+			continue
+		}
+		if cur.Len() > 0 {
+			cur.WriteByte('\n')
+		}
+		if strings.HasSuffix(trimmed, ";") {
+			cur.WriteString(strings.TrimSuffix(strings.TrimRight(line, " \t"), ";"))
+			stmts = append(stmts, cur.String())
+			cur.Reset()
+			continue
+		}
+		cur.WriteString(line)
+	}
+	if s := strings.TrimSpace(cur.String()); s != "" {
+		stmts = append(stmts, s)
+	}
+	return stmts
+}
+
+// Migrate 按版本号顺序执行尚未执行的迁移，返回本次执行的迁移
+func Migrate(ctx context.Context, db *sql.DB) ([]Migration, error) {
+	migrations, err := Migrations()
+	if err != nil {
+		return nil, err
+	}
+
+	// 命名锁属于连接，加锁、迁移和解锁需要使用同一个连接
+	conn, err := db.Conn(ctx)
+	if err != nil {
+		return nil, err
+	}
+	defer conn.Close()
+
+	var locked sql.NullInt64
+	if err := conn.QueryRowContext(ctx, `SELECT GET_LOCK(?, 60)`, migrateLock).Scan(&locked); err != nil {
+		return nil, fmt.Errorf("获取迁移锁失败: %v", err)
+	}
+	if locked.Int64 != 1 {
+		return nil, fmt.Errorf("获取迁移锁超时，可能有其他实例正在执行迁移")
+	}
+	defer conn.ExecContext(context.Background(), `SELECT RELEASE_LOCK(?)`, migrateLock)
+
+	if _, err := conn.ExecContext(ctx, createMigrationsTable); err != nil {
+		return nil, fmt.Errorf("创建 jirahook_schema_migrations 失败: %v", err)
+	}
+	applied, err := appliedVersions(ctx, conn)
+	if err != nil {
+		return nil, err
+	}
+
+	var done []Migration
+	for _, m := range migrations {
+		if _, ok := applied[m.Version]; ok {
+			continue
+		}
+		// MySQL 的 DDL 无法回滚，失败时需要人工处理后重新执行
+		for _, stmt := range m.Statements {
+			if _, err := conn.ExecContext(ctx, stmt); err != nil {
+				return done, fmt.Errorf("执行迁移 %04d_%s 失败: %v", m.Version, m.Name, err)
+			}
+		}
+		if _, err := conn.ExecContext(ctx,
+			`INSERT INTO jirahook_schema_migrations (version, name, applied_at) VALUES (?, ?, ?)`,
+			m.Version, m.Name, time.Now()); err != nil {
+			return done, fmt.Errorf("记录迁移 %04d_%s 失败: %v", m.Version, m.Name, err)
+		}
+		done = append(done, m)
+	}
+	return done, nil
+}
+
+// Status 返回每个迁移的执行情况
+func Status(ctx context.Context, db *sql.DB) ([]MigrationStatus, error) {
+	migrations, err := Migrations()
+	if err != nil {
+		return nil, err
+	}
+	conn, err := db.Conn(ctx)
+	if err != nil {
+		return nil, err
+	}
+	defer conn.Close()
+
+	if _, err := conn.ExecContext(ctx, createMigrationsTable); err != nil {
+		return nil, fmt.Errorf("创建 jirahook_schema_migrations 失败: %v", err)
+	}
+	applied, err := appliedVersions(ctx, conn)
+	if err != nil {
+		return nil, err
+	}
+
+	status := make([]MigrationStatus, 0, len(migrations))
+	for _, m := range migrations {
+		s := MigrationStatus{Migration: m}
+		if at, ok := applied[m.Version]; ok {
+			s.AppliedAt = &at
+		}
+		status = append(status, s)
+	}
+	return status, nil
+}
+
+// appliedVersions 返回已执行的迁移版本及执行时间
+func appliedVersions(ctx context.Context, conn *sql.Conn) (map[int]time.Time, error) {
+	rows, err := conn.QueryContext(ctx, `SELECT version, applied_at FROM jirahook_schema_migrations`)
+	if err != nil {
+		return nil, fmt.Errorf("读取已执行的迁移失败: %v", err)
+	}
+	defer rows.Close()
+
+	applied := make(map[int]time.Time)
+	for rows.Next() {
+		var version int
+		var at time.Time
+		if err := rows.Scan(&version, &at); err != nil {
+			return nil, err
+		}
+		applied[version] = at
+	}
+	return applied, rows.Err()
+}
@@ -0,0 +1,52 @@
+package store
+
+import (
+	"reflect"
+	"strings"
+	"testing"
+	"testing/fstest"
+)
+
+func TestEmbeddedMigrations(t *testing.T) {
+	migrations, err := Migrations()
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(migrations) == 0 {
+		t.Fatal("no migrations embedded")
+	}
+	for i, m := range migrations {
+		if m.Version != i+1 {
+			t.Errorf("migration %s has version %d, want %d", m.Name, m.Version, i+1)
+		}
+		for _, stmt := range m.Statements {
+			if strings.HasSuffix(stmt, ";") {
+				t.Errorf("migration %s statement keeps trailing semicolon", m.Name)
+			}
+		}
+	}
+}
+
+func TestLoadMigrations(t *testing.T) {
+	fsys := fstest.MapFS{
+		"m/0002_second.sql": {Data: []byte("-- 注释\nALTER TABLE t\n    ADD COLUMN b INT;\n")},
+		"m/0001_first.sql":  {Data: []byte("CREATE TABLE a (id INT);\n\nCREATE TABLE b (id INT);\n")},
+		"m/README.md":       {Data: []byte("ignored")},
+	}
+	migrations, err := loadMigrations(fsys, "m")
+	if err != nil {
+		t.Fatal(err)
+	}
+	want := []Migration{
+		{Version: 1, Name: "first", Statements: []string{"CREATE TABLE a (id INT)", "CREATE TABLE b (id INT)"}},
+		{Version: 2, Name: "second", Statements: []string{"ALTER TABLE t\n    ADD COLUMN b INT"}},
+	}
+	if !reflect.DeepEqual(migrations, want) {
+		t.Errorf("got %+v\nwant %+v", migrations, want)
+	}
+
+	fsys["m/0001_again.sql"] = &fstest.MapFile{Data: []byte("SELECT 1;")}
+	if _, err := loadMigrations(fsys, "m"); err == nil || !strings.Contains(err.Error(), "重复") {
+		t.Errorf("duplicate version error = %v", err)
+	}
+}
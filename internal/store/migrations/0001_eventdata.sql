@@ -0,0 +1,17 @@
+-- 事件流水，早期部署中由人工创建，已存在时保持不变
+CREATE TABLE IF NOT EXISTS jirahook_eventdata (
+    id               BIGINT       NOT NULL AUTO_INCREMENT PRIMARY KEY,
+    event_type       VARCHAR(32)  NOT NULL DEFAULT '',
+    summary_key_id   VARCHAR(64)  NOT NULL DEFAULT '',
+    operator         VARCHAR(255) NOT NULL DEFAULT '',
+    assignee_phone   VARCHAR(64)  NOT NULL DEFAULT '',
+    reporter_phone   VARCHAR(64)  NOT NULL DEFAULT '',
+    rpt_from         VARCHAR(255) NOT NULL DEFAULT '',
+    rpt_to           VARCHAR(255) NOT NULL DEFAULT '',
+    assigner_from_to VARCHAR(512) NOT NULL DEFAULT '',
+    status           VARCHAR(64)  NOT NULL DEFAULT '',
+    status_from      VARCHAR(64)  NOT NULL DEFAULT '',
+    status_to        VARCHAR(64)  NOT NULL DEFAULT '',
+    summary          VARCHAR(512) NOT NULL DEFAULT '',
+    created_at       DATETIME     NOT NULL DEFAULT CURRENT_TIMESTAMP
+) DEFAULT CHARSET = utf8mb4;
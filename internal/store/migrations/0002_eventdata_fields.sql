@@ -0,0 +1,17 @@
+-- 早期人工创建的表可能没有 created_at，缺少时先补上，MySQL 不支持 ADD COLUMN IF NOT EXISTS，用预处理语句按需执行
+SET @add_created_at = IF(
+    (SELECT COUNT(*) FROM information_schema.COLUMNS
+     WHERE TABLE_SCHEMA = DATABASE() AND TABLE_NAME = 'jirahook_eventdata' AND COLUMN_NAME = 'created_at') = 0,
+    'ALTER TABLE jirahook_eventdata ADD COLUMN created_at DATETIME NOT NULL DEFAULT CURRENT_TIMESTAMP',
+    'DO 0');
+PREPARE add_created_at FROM @add_created_at;
+EXECUTE add_created_at;
+DEALLOCATE PREPARE add_created_at;
+
+-- 记录通用字段变更，并为按问题和时间查询建立索引
+ALTER TABLE jirahook_eventdata
+    ADD COLUMN field      VARCHAR(128)  NOT NULL DEFAULT '',
+    ADD COLUMN field_from VARCHAR(1024) NOT NULL DEFAULT '',
+    ADD COLUMN field_to   VARCHAR(1024) NOT NULL DEFAULT '',
+    ADD KEY idx_summary_key_id (summary_key_id),
+    ADD KEY idx_created_at (created_at);
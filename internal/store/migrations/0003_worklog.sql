@@ -0,0 +1,17 @@
+CREATE TABLE IF NOT EXISTS jirahook_worklog (
+    worklog_id          VARCHAR(32)  NOT NULL PRIMARY KEY,
+    issue_id            VARCHAR(32)  NOT NULL DEFAULT '',
+    issue_key           VARCHAR(64)  NOT NULL DEFAULT '',
+    issue_summary       VARCHAR(512) NOT NULL DEFAULT '',
+    author_account_id   VARCHAR(128) NOT NULL DEFAULT '',
+    author_key          VARCHAR(255) NOT NULL DEFAULT '',
+    author_name         VARCHAR(255) NOT NULL DEFAULT '',
+    author_email        VARCHAR(255) NOT NULL DEFAULT '',
+    author_display_name VARCHAR(255) NOT NULL DEFAULT '',
+    started             DATETIME     NOT NULL,
+    time_spent_seconds  INT          NOT NULL DEFAULT 0,
+    created             DATETIME     NULL,
+    updated             DATETIME     NULL,
+    KEY idx_started (started),
+    KEY idx_created (created)
+) DEFAULT CHARSET = utf8mb4;
@@ -0,0 +1,39 @@
+-- 问题快照，供 Sprint 报告和发布说明使用
+CREATE TABLE IF NOT EXISTS jirahook_issue (
+    issue_id    VARCHAR(32)  NOT NULL PRIMARY KEY,
+    issue_key   VARCHAR(64)  NOT NULL,
+    summary     VARCHAR(512) NOT NULL DEFAULT '',
+    issue_type  VARCHAR(64)  NOT NULL DEFAULT '',
+    status      VARCHAR(64)  NOT NULL DEFAULT '',
+    done        TINYINT(1)   NOT NULL DEFAULT 0,
+    assignee    VARCHAR(255) NOT NULL DEFAULT '',
+    self        VARCHAR(512) NOT NULL DEFAULT '',
+    deleted     TINYINT(1)   NOT NULL DEFAULT 0,
+    updated_at  DATETIME     NOT NULL,
+    KEY idx_issue_key (issue_key)
+) DEFAULT CHARSET = utf8mb4;
+
+CREATE TABLE IF NOT EXISTS jirahook_sprint_issue (
+    sprint_id   INT          NOT NULL,
+    issue_id    VARCHAR(32)  NOT NULL,
+    added_at    DATETIME     NOT NULL,
+    removed_at  DATETIME     NULL,
+    PRIMARY KEY (sprint_id, issue_id)
+) DEFAULT CHARSET = utf8mb4;
+
+CREATE TABLE IF NOT EXISTS jirahook_version (
+    version_id   VARCHAR(32)   NOT NULL PRIMARY KEY,
+    name         VARCHAR(255)  NOT NULL DEFAULT '',
+    description  VARCHAR(1024) NOT NULL DEFAULT '',
+    project_id   INT           NOT NULL DEFAULT 0,
+    released     TINYINT(1)    NOT NULL DEFAULT 0,
+    release_date VARCHAR(32)   NOT NULL DEFAULT ''
+) DEFAULT CHARSET = utf8mb4;
+
+CREATE TABLE IF NOT EXISTS jirahook_issue_version (
+    version_id  VARCHAR(32)  NOT NULL,
+    issue_id    VARCHAR(32)  NOT NULL,
+    project_key VARCHAR(64)  NOT NULL DEFAULT '',
+    PRIMARY KEY (version_id, issue_id),
+    KEY idx_issue_id (issue_id)
+) DEFAULT CHARSET = utf8mb4;
@@ -0,0 +1,13 @@
+-- 重试次数用尽的钉钉消息
+CREATE TABLE IF NOT EXISTS jirahook_dead_letter (
+    id         BIGINT       NOT NULL AUTO_INCREMENT PRIMARY KEY,
+    robot      VARCHAR(64)  NOT NULL,
+    content    MEDIUMTEXT   NOT NULL,
+    mentions   TEXT         NOT NULL,
+    attempts   INT          NOT NULL,
+    last_error TEXT         NOT NULL,
+    created_at DATETIME     NOT NULL,
+    dead_at    DATETIME     NOT NULL,
+    resent_at  DATETIME     NULL,
+    KEY idx_resent_at (resent_at)
+) DEFAULT CHARSET = utf8mb4;
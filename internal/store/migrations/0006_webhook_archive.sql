@@ -0,0 +1,12 @@
+-- webhook 原始请求存档，body 使用 gzip 压缩
+CREATE TABLE IF NOT EXISTS jirahook_webhook_archive (
+    id          BIGINT       NOT NULL AUTO_INCREMENT PRIMARY KEY,
+    received_at DATETIME(3)  NOT NULL,
+    event       VARCHAR(64)  NOT NULL,
+    action      VARCHAR(64)  NOT NULL,
+    issue_key   VARCHAR(64)  NOT NULL,
+    headers     TEXT         NOT NULL,
+    body        MEDIUMBLOB   NOT NULL,
+    KEY idx_received_at (received_at),
+    KEY idx_issue_key (issue_key)
+) DEFAULT CHARSET = utf8mb4;
@@ -0,0 +1,2 @@
+-- added_at 只记录变更记录中问题加入 Sprint 的时间，首次见到时已在 Sprint 中的问题为 NULL
+ALTER TABLE jirahook_sprint_issue MODIFY added_at DATETIME NULL;
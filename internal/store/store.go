@@ -0,0 +1,44 @@
+package store
+
+import (
+	"context"
+	"database/sql"
+	"fmt"
+	"whenchangesth/internal/conf"
+
+	_ "github.com/go-sql-driver/mysql"
+)
+
+// Open 创建 MySQL 连接池并确认可以连接，连接池在进程内长期使用
+func Open(ctx context.Context, cfg conf.MySQLConfig) (*sql.DB, error) {
+	db, err := Connect(cfg)
+	if err != nil {
+		return nil, err
+	}
+	if err := db.PingContext(ctx); err != nil {
+		db.Close()
+		return nil, fmt.Errorf("数据库 Ping 失败: %v", err)
+	}
+	return db, nil
+}
+
+// Connect 创建 MySQL 连接池但不建立连接，MySQL 暂时不可用时连接池在恢复后自动重连
+func Connect(cfg conf.MySQLConfig) (*sql.DB, error) {
+	dsn := fmt.Sprintf("%s:%s@tcp(%s:%d)/%s?charset=utf8mb4&parseTime=true&loc=Local",
+		cfg.User,
+		cfg.Password,
+		cfg.Host,
+		cfg.Port,
+		cfg.Database,
+	)
+
+	db, err := sql.Open("mysql", dsn)
+	if err != nil {
+		return nil, fmt.Errorf("打开数据库失败: %v", err)
+	}
+	db.SetMaxOpenConns(cfg.MaxOpenConns)
+	db.SetMaxIdleConns(cfg.MaxIdleConns)
+	db.SetConnMaxLifetime(cfg.ConnMaxLifetime)
+	db.SetConnMaxIdleTime(cfg.ConnMaxIdleTime)
+	return db, nil
+}
@@ -0,0 +1,166 @@
+package timesheet
+
+import (
+	"fmt"
+	"sort"
+	"strings"
+	"time"
+)
+
+// Entry 是一条已归属到人的工时记录
+type Entry struct {
+	WorklogID string
+	IssueKey  string
+	Summary   string
+	// Person 是通讯录中的姓名
+	Person  string
+	Started time.Time
+	Created time.Time
+	Seconds int
+}
+
+// Limits 是判断可疑记录的阈值
+type Limits struct {
+	MaxDaily      time.Duration
+	BackdateLimit time.Duration
+}
+
+// IssueHours 是某人在某个问题上的工时
+type IssueHours struct {
+	Key     string
+	Summary string
+	Seconds int
+}
+
+// PersonHours 是某人当天的工时
+type PersonHours struct {
+	Name    string
+	Seconds int
+	Issues  []IssueHours
+}
+
+// Flag 是一条需要关注的记录
+type Flag struct {
+	Person string
+	Reason string
+}
+
+// Report 是某个团队一天的工时汇总
+type Report struct {
+	Team   string
+	Date   time.Time
+	People []PersonHours
+	// Missing 是当天没有登记工时的成员
+	Missing []string
+	Flags   []Flag
+	// Mentions 是需要 @ 的手机号或钉钉 userId，钉钉只提醒正文中出现了 @ 的人
+	Mentions []string
+}
+
+// DayRange 返回 date 所在自然日的起止时间
+func DayRange(date time.Time) (start, end time.Time) {
+	start = time.Date(date.Year(), date.Month(), date.Day(), 0, 0, 0, 0, date.Location())
+	return start, start.AddDate(0, 0, 1)
+}
+
+// Build 汇总团队成员在 date 当天的工时
+// 开始时间在当天的记录计入工时，当天登记但开始时间早于补录期限的记录标记为可疑
+func Build(team string, date time.Time, members []string, entries []Entry, limits Limits) Report {
+	start, end := DayRange(date)
+	r := Report{Team: team, Date: start}
+
+	byPerson := make(map[string]*PersonHours)
+	for _, e := range entries {
+		if !e.Created.IsZero() && !e.Created.Before(start) && e.Created.Before(end) &&
+			e.Created.Sub(e.Started) > limits.BackdateLimit {
+			r.Flags = append(r.Flags, Flag{
+				Person: e.Person,
+				Reason: fmt.Sprintf("%s 补录了 %s 的 %s", issueName(e.IssueKey, e.Summary), e.Started.Format("01-02"), Hours(e.Seconds)),
+			})
+		}
+		if e.Started.Before(start) || !e.Started.Before(end) {
+			continue
+		}
+
+		p, ok := byPerson[e.Person]
+		if !ok {
+			p = &PersonHours{Name: e.Person}
+			byPerson[e.Person] = p
+		}
+		p.Seconds += e.Seconds
+		p.Issues = addIssue(p.Issues, e)
+	}
+
+	for _, p := range byPerson {
+		sort.Slice(p.Issues, func(i, j int) bool { return p.Issues[i].Key < p.Issues[j].Key })
+		r.People = append(r.People, *p)
+		if time.Duration(p.Seconds)*time.Second > limits.MaxDaily {
+			r.Flags = append(r.Flags, Flag{Person: p.Name, Reason: fmt.Sprintf("当天登记 %s，超过 %s", Hours(p.Seconds), Hours(int(limits.MaxDaily.Seconds())))})
+		}
+	}
+	sort.Slice(r.People, func(i, j int) bool { return r.People[i].Name < r.People[j].Name })
+	sort.SliceStable(r.Flags, func(i, j int) bool { return r.Flags[i].Person < r.Flags[j].Person })
+
+	for _, name := range members {
+		if _, ok := byPerson[name]; !ok {
+			r.Missing = append(r.Missing, name)
+		}
+	}
+	sort.Strings(r.Missing)
+	return r
+}
+
+// addIssue 将记录累加到对应问题
+func addIssue(issues []IssueHours, e Entry) []IssueHours {
+	for i := range issues {
+		if issues[i].Key == e.IssueKey {
+			issues[i].Seconds += e.Seconds
+			return issues
+		}
+	}
+	return append(issues, IssueHours{Key: e.IssueKey, Summary: e.Summary, Seconds: e.Seconds})
+}
+
+// issueName 返回问题的展示名称
+func issueName(key, summary string) string {
+	if summary == "" {
+		return key
+	}
+	return key + " " + summary
+}
+
+// Hours 将秒数格式化为小时，例如 7.5h
+func Hours(seconds int) string {
+	return strings.TrimSuffix(strings.TrimRight(fmt.Sprintf("%.2f", float64(seconds)/3600), "0"), ".") + "h"
+}
+
+// Markdown 将汇总转换为钉钉 markdown 消息
+func (r Report) Markdown() string {
+	var b strings.Builder
+	fmt.Fprintf(&b, "### **工时日报: %s %s**\n", r.Team, r.Date.Format("2006-01-02"))
+	for _, p := range r.People {
+		fmt.Fprintf(&b, "- **%s**: %s\n", p.Name, Hours(p.Seconds))
+		for _, i := range p.Issues {
+			fmt.Fprintf(&b, "    - %s: %s\n", issueName(i.Key, i.Summary), Hours(i.Seconds))
+		}
+	}
+	if len(r.People) == 0 {
+		b.WriteString("- 当天没有登记工时\n")
+	}
+	if len(r.Missing) > 0 {
+		fmt.Fprintf(&b, "\n**未登记工时**: %s\n", strings.Join(r.Missing, "、"))
+	}
+	if len(r.Flags) > 0 {
+		b.WriteString("\n**需要确认**:\n")
+		for _, f := range r.Flags {
+			fmt.Fprintf(&b, "- %s: %s\n", f.Person, f.Reason)
+		}
+	}
+	if len(r.Mentions) > 0 {
+		b.WriteString("\n")
+		for _, m := range r.Mentions {
+			fmt.Fprintf(&b, "@%s ", m)
+		}
+	}
+	return b.String()
+}
@@ -0,0 +1,62 @@
+package timesheet
+
+import (
+	"strings"
+	"testing"
+	"time"
+)
+
+func TestBuild(t *testing.T) {
+	day := time.Date(2026, 10, 16, 0, 0, 0, 0, time.Local)
+	at := func(d, h int) time.Time { return day.AddDate(0, 0, d).Add(time.Duration(h) * time.Hour) }
+	entries := []Entry{
+		{IssueKey: "ABC-1", Person: "张三", Started: at(0, 9), Created: at(0, 12), Seconds: 3 * 3600},
+		{IssueKey: "ABC-2", Person: "张三", Started: at(0, 13), Created: at(0, 18), Seconds: 5400},
+		{IssueKey: "ABC-1", Person: "张三", Started: at(0, 15), Created: at(0, 18), Seconds: 1800},
+		{IssueKey: "ABC-3", Person: "李四", Started: at(0, 0), Created: at(0, 23), Seconds: 13 * 3600},
+		{IssueKey: "ABC-4", Person: "王五", Started: at(-10, 9), Created: at(0, 10), Seconds: 3600},
+		{IssueKey: "ABC-5", Person: "王五", Started: at(-1, 9), Created: at(-1, 10), Seconds: 3600},
+	}
+	r := Build("后端", day.Add(15*time.Hour), []string{"张三", "李四", "王五", "赵六"}, entries, Limits{
+		MaxDaily:      12 * time.Hour,
+		BackdateLimit: 7 * 24 * time.Hour,
+	})
+
+	if len(r.People) != 2 || r.People[0].Name != "张三" || r.People[0].Seconds != 5*3600 || len(r.People[0].Issues) != 2 {
+		t.Fatalf("people = %+v", r.People)
+	}
+	if r.People[0].Issues[0].Key != "ABC-1" || r.People[0].Issues[0].Seconds != 3*3600+1800 {
+		t.Fatalf("issues = %+v", r.People[0].Issues)
+	}
+	if len(r.Missing) != 2 || r.Missing[0] != "王五" || r.Missing[1] != "赵六" {
+		t.Fatalf("missing = %v", r.Missing)
+	}
+	if len(r.Flags) != 2 || r.Flags[0].Person != "李四" || r.Flags[1].Person != "王五" {
+		t.Fatalf("flags = %+v", r.Flags)
+	}
+}
+
+func TestHours(t *testing.T) {
+	cases := map[int]string{0: "0h", 3600: "1h", 5400: "1.5h", 900: "0.25h", 1200: "0.33h"}
+	for seconds, want := range cases {
+		if got := Hours(seconds); got != want {
+			t.Errorf("Hours(%d) = %q, want %q", seconds, got, want)
+		}
+	}
+}
+
+func TestMarkdownMentionsMissing(t *testing.T) {
+	r := Report{
+		Team:     "后端",
+		Date:     time.Date(2026, 10, 16, 0, 0, 0, 0, time.Local),
+		Missing:  []string{"王五", "赵六"},
+		Mentions: []string{"13800000000", "ding_zhaoliu"},
+	}
+	md := r.Markdown()
+	if !strings.Contains(md, "**未登记工时**: 王五、赵六") {
+		t.Errorf("missing names not listed:\n%s", md)
+	}
+	if !strings.HasSuffix(md, "\n@13800000000 @ding_zhaoliu ") {
+		t.Errorf("mentions not in text:\n%q", md)
+	}
+}
@@ -64,7 +64,7 @@ type IssueAssignedPayload issueUpdatedPayload
 
 type IssueCommentCreatedPayload issueCommentPayload
 type IssueCommentUpdatedPayload issueCommentPayload
-type IssueCommentDeletedPayload issuePayload
+type IssueCommentDeletedPayload issueCommentPayload
 
 type IssueWorkLogCreatedPayload issueUpdatedPayload
 type IssueWorkLogUpdatedPayload issueUpdatedPayload
@@ -90,6 +90,7 @@ type WorkLogDeletedPayload workLogPayload
 type commentPayload struct {
 	Time    objects.Timestamp `json:"timestamp"`
 	Comment *objects.Comment  `json:"comment"`
+	Issue   *objects.Issue    `json:"issue"`
 }
 
 type CommentCreatedPayload commentPayload